@@ -0,0 +1,147 @@
+package main
+
+// ClearEffect is how cleared rows are animated.
+type ClearEffect int
+
+const (
+	ClearOff ClearEffect = iota
+	ClearFlash
+	ClearWipe
+)
+
+var clearEffectNames = []string{"Off", "Flash", "Wipe"}
+
+func (e ClearEffect) String() string {
+	return clearEffectNames[e]
+}
+
+// Animation lengths in ticks
+const (
+	clearTicks       = 20
+	lockTicks        = 8
+	levelBannerTicks = 90
+	dropTrailTicks   = 10
+	topOutRowTicks   = 2 // the field fills one row every two ticks
+)
+
+// Animation is an effect started by a board event. It only lives in the
+// renderer, so the game never waits for it.
+type Animation struct {
+	Event    BoardEvent
+	Age      int
+	Duration int
+}
+
+// Progress goes from 0 when the animation starts to 1 when it ends.
+func (a *Animation) Progress() float64 {
+	return min(float64(a.Age)/float64(a.Duration), 1)
+}
+
+// Animator turns board events into the animations the settings ask for
+// and ages them one step every tick.
+type Animator struct {
+	settings *Settings
+	rows     int
+	Active   []*Animation
+	Effects  *Effects
+}
+
+func NewAnimator(settings *Settings, rows int) *Animator {
+	return &Animator{settings: settings, rows: rows, Effects: NewEffects()}
+}
+
+// Watch starts animating the events of a board, dropping the animations of
+// the previous one.
+func (a *Animator) Watch(b *Board) {
+	a.Active = a.Active[:0]
+	a.Effects.Clear()
+	b.SetListener(a.Push)
+}
+
+// Push starts the animation and effects for an event, if they are turned on.
+func (a *Animator) Push(e BoardEvent) {
+	a.pushEffects(e)
+
+	duration := 0
+	switch e.Kind {
+	case EventClear:
+		if a.settings.ClearEffect != ClearOff {
+			duration = clearTicks
+		}
+	case EventLock:
+		if a.settings.LockFlash {
+			duration = lockTicks
+		}
+	case EventLevelUp:
+		if a.settings.LevelBanner {
+			duration = levelBannerTicks
+		}
+	case EventHardDrop:
+		if a.settings.DropTrail && e.Distance > 0 {
+			duration = dropTrailTicks
+		}
+	case EventTopOut:
+		if a.settings.TopOutFill && !a.Running(EventTopOut) {
+			duration = a.rows * topOutRowTicks
+		}
+	}
+
+	if duration > 0 {
+		a.Active = append(a.Active, &Animation{Event: e, Duration: duration})
+	}
+}
+
+// pushEffects starts the particles and shake for an event, unless the
+// player asked for less motion.
+func (a *Animator) pushEffects(e BoardEvent) {
+	if a.settings.ReduceMotion {
+		return
+	}
+
+	switch e.Kind {
+	case EventClear:
+		a.Effects.burst(e.Rows, e.RowColors, e.RowKinds)
+		if len(e.Rows) >= 4 {
+			a.Effects.Shake(tetrisShake)
+		}
+	case EventHardDrop:
+		if e.Distance > 0 {
+			a.Effects.Shake(min(1+float64(e.Distance)/8, maxHardDropShake))
+		}
+	}
+}
+
+// Update ages the animations by a tick and drops the finished ones.
+func (a *Animator) Update() {
+	active := a.Active[:0]
+	for _, anim := range a.Active {
+		anim.Age++
+		if anim.Age < anim.Duration {
+			active = append(active, anim)
+		}
+	}
+	clear(a.Active[len(active):])
+	a.Active = active
+	if a.settings.ReduceMotion {
+		a.Effects.Clear()
+	}
+	a.Effects.Update()
+}
+
+// Running reports whether an animation of the kind is playing.
+func (a *Animator) Running(kind BoardEventKind) bool {
+	for _, anim := range a.Active {
+		if anim.Event.Kind == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Skip ends every animation and effect at once.
+func (a *Animator) Skip() {
+	clear(a.Active)
+	a.Active = a.Active[:0]
+	a.Effects.Clear()
+}
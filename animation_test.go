@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestBoard_Events(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xxxxxxxxx.
+xxxxxxxxx.`))
+
+	var events []BoardEvent
+	b.SetListener(func(e BoardEvent) { events = append(events, e) })
+
+	// A vertical I piece in the last column drops and clears two lines
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceI], x: 9., y: 1.}
+	b.Fall()
+
+	kinds := []BoardEventKind{EventHardDrop, EventLock, EventClear}
+	if len(events) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), events)
+	}
+	for i, kind := range kinds {
+		if events[i].Kind != kind {
+			t.Errorf("Expected event %d to be %d, got %d", i, kind, events[i].Kind)
+		}
+	}
+
+	if d := events[0].Distance; d != rows-4 {
+		t.Errorf("Expected the piece to drop %d rows, got %d", rows-4, d)
+	}
+
+	clear := events[2]
+	if len(clear.Rows) != 2 || clear.Rows[0] != rows-1 || clear.Rows[1] != rows-2 {
+		t.Errorf("Expected the bottom two rows to be cleared, got %v", clear.Rows)
+	}
+	if len(clear.RowColors) != 2 || clear.RowColors[0][9] != colorI {
+		t.Errorf("Expected the colours of the cleared rows, got %v", clear.RowColors)
+	}
+}
+
+func TestBoard_LevelUpAndTopOutEvents(t *testing.T) {
+	b := NewBoard(rows, cols)
+
+	var events []BoardEvent
+	b.SetListener(func(e BoardEvent) { events = append(events, e) })
+
+	b.totalNumberOfLinesCleared = 10
+	b.Tick()
+	b.AddGarbage(rows)
+	b.AddGarbage(1)
+
+	if len(events) != 2 || events[0].Kind != EventLevelUp || events[0].Level != 1 || events[1].Kind != EventTopOut {
+		t.Errorf("Expected a level up and a top out, got %+v", events)
+	}
+}
+
+func TestAnimator(t *testing.T) {
+	settings := DefaultSettings()
+	settings.LockFlash = false
+	a := NewAnimator(settings, rows)
+
+	a.Push(BoardEvent{Kind: EventLock})
+	if len(a.Active) != 0 {
+		t.Fatalf("Expected no lock flash when it is turned off")
+	}
+
+	a.Push(BoardEvent{Kind: EventClear, Rows: []int{rows - 1}})
+	a.Push(BoardEvent{Kind: EventTopOut})
+	a.Push(BoardEvent{Kind: EventTopOut})
+	if len(a.Active) != 2 {
+		t.Fatalf("Expected a clear and one top out animation, got %d", len(a.Active))
+	}
+
+	for range clearTicks {
+		a.Update()
+	}
+	if a.Running(EventClear) || !a.Running(EventTopOut) {
+		t.Errorf("Expected the clear to be over and the top out to go on")
+	}
+
+	a.Skip()
+	if a.Running(EventTopOut) {
+		t.Errorf("Expected skipping to end the top out")
+	}
+}
+
+func TestAnimator_Effects(t *testing.T) {
+	settings := DefaultSettings()
+	a := NewAnimator(settings, rows)
+
+	row := make([]color.Color, cols)
+	row[3] = colorT
+	row[4] = colorL
+	a.Push(BoardEvent{Kind: EventClear, Rows: []int{5, 6, 7, 8}, RowColors: [][]color.Color{row, row, row, row}})
+
+	if got := len(a.Effects.Particles); got != 8*particlesPerCell {
+		t.Errorf("Expected %d particles, got %d", 8*particlesPerCell, got)
+	}
+	if x, y := a.Effects.Offset(); x == 0 && y == 0 {
+		t.Errorf("Expected a tetris to shake the board")
+	}
+
+	for range maxParticleLife {
+		a.Update()
+	}
+	if len(a.Effects.Particles) != 0 {
+		t.Errorf("Expected the particles to be gone, got %d", len(a.Effects.Particles))
+	}
+
+	settings.ReduceMotion = true
+	a.Push(BoardEvent{Kind: EventClear, Rows: []int{5}, RowColors: [][]color.Color{row}})
+	a.Push(BoardEvent{Kind: EventHardDrop, Distance: 10})
+	if x, y := a.Effects.Offset(); len(a.Effects.Particles) != 0 || x != 0 || y != 0 {
+		t.Errorf("Expected no particles or shake with reduced motion")
+	}
+}
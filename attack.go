@@ -0,0 +1,116 @@
+package main
+
+// ClearInfo describes the lines cleared by the last piece that locked.
+type ClearInfo struct {
+	Lines        int
+	TSpin        bool
+	Combo        int // number of clearing locks in a row before this one
+	BackToBack   bool
+	PerfectClear bool
+}
+
+var (
+	// Garbage sent for clearing 0 to 4 lines
+	lineAttack  = []int{0, 0, 1, 2, 4}
+	tSpinAttack = []int{0, 2, 4, 6, 6}
+	// Extra garbage for the 1st, 2nd, ... clear of a combo
+	comboAttack = []int{0, 0, 1, 1, 1, 2, 2, 3, 3, 4, 4, 4, 5}
+)
+
+const (
+	backToBackAttack   = 1
+	perfectClearAttack = 10
+)
+
+// Attack returns the number of garbage lines the clear sends to an opponent.
+func (c ClearInfo) Attack() int {
+	if c.Lines == 0 {
+		return 0
+	}
+
+	lines := min(c.Lines, 4)
+	attack := lineAttack[lines]
+	if c.TSpin {
+		attack = tSpinAttack[lines]
+	}
+
+	attack += comboAttack[min(c.Combo, len(comboAttack)-1)]
+
+	if c.BackToBack {
+		attack += backToBackAttack
+	}
+
+	if c.PerfectClear {
+		attack += perfectClearAttack
+	}
+
+	return attack
+}
+
+// Name returns how the clear is announced, e.g. "T-SPIN DOUBLE".
+func (c ClearInfo) Name() string {
+	names := []string{"", "SINGLE", "DOUBLE", "TRIPLE", "TETRIS"}
+	name := names[min(c.Lines, 4)]
+
+	if c.TSpin {
+		name = "T-SPIN " + name
+	}
+
+	if c.BackToBack {
+		name = "B2B " + name
+	}
+
+	return name
+}
+
+// recordClear updates the combo and back-to-back state after a lock and
+// turns its clear into attack. Clears always cancel at least as many pending
+// garbage lines as they clear; attack not used for cancelling is sent out.
+func (b *Board) recordClear(lines int, tSpin bool) {
+	if lines == 0 {
+		b.combo = -1
+		return
+	}
+
+	b.combo++
+	difficult := lines >= 4 || tSpin
+
+	b.lastClear = ClearInfo{
+		Lines:        lines,
+		TSpin:        tSpin,
+		Combo:        b.combo,
+		BackToBack:   difficult && b.backToBack,
+		PerfectClear: b.isFieldEmpty(),
+	}
+	b.backToBack = difficult
+
+	attack := b.lastClear.Attack()
+	left := b.cancelGarbage(max(lines, attack))
+	b.outgoingGarbage += min(attack, left)
+}
+
+// isTSpin checks the T-spin rule for the piece that has just locked: a T
+// piece whose last move was a rotation, with three of the four corners around
+// its centre blocked.
+func (b *Board) isTSpin() bool {
+	p := b.currentPiece
+	if p.piece.kind != PieceT || !b.lastMoveRotate {
+		return false
+	}
+
+	blocked := 0
+	for _, corner := range [][2]int{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		x := int(p.x) + corner[0]
+		y := int(p.y) + corner[1]
+
+		if x < 0 || x >= cols || y >= rows || (y >= 0 && b.field.Filled(x, y)) {
+			blocked++
+		}
+	}
+
+	return blocked >= 3
+}
+
+func (b *Board) isFieldEmpty() bool {
+	return b.field.IsEmpty()
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestClearInfo_Attack(t *testing.T) {
+	tests := []struct {
+		name   string
+		clear  ClearInfo
+		attack int
+	}{
+		{name: "nothing", clear: ClearInfo{}, attack: 0},
+		{name: "single", clear: ClearInfo{Lines: 1}, attack: 0},
+		{name: "double", clear: ClearInfo{Lines: 2}, attack: 1},
+		{name: "triple", clear: ClearInfo{Lines: 3}, attack: 2},
+		{name: "tetris", clear: ClearInfo{Lines: 4}, attack: 4},
+		{name: "back-to-back tetris", clear: ClearInfo{Lines: 4, BackToBack: true}, attack: 5},
+		{name: "t-spin double", clear: ClearInfo{Lines: 2, TSpin: true}, attack: 4},
+		{name: "double in a 4 combo", clear: ClearInfo{Lines: 2, Combo: 4}, attack: 2},
+		{name: "perfect clear single", clear: ClearInfo{Lines: 1, PerfectClear: true}, attack: 10},
+	}
+
+	for _, tt := range tests {
+		if got := tt.clear.Attack(); got != tt.attack {
+			t.Errorf("%s: expected attack %d, got %d", tt.name, tt.attack, got)
+		}
+	}
+}
+
+func TestTSpinDouble(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xx........
+x...xxxxxx
+xx.xxxxxxx`))
+
+	// T piece pointing down, rotated into the slot
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceT], x: 2., y: rows - 2., state: 1}
+	b.Rotate()
+	b.Fall()
+
+	if !b.lastClear.TSpin || b.lastClear.Lines != 2 {
+		t.Fatalf("Expected a T-spin double, got %+v", b.lastClear)
+	}
+
+	if sent := b.TakeOutgoingGarbage(); sent != 4 {
+		t.Errorf("Expected 4 lines of attack, got %d", sent)
+	}
+}
+
+func TestCombo(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xxxxxxxx..
+..xxxxxxxx`))
+
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceO], x: 8., y: rows - 3.}
+	b.Fall()
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceO], x: 0., y: rows - 2.}
+	b.Fall()
+
+	if b.totalNumberOfLinesCleared != 2 {
+		t.Fatalf("Expected 2 lines cleared, got %d", b.totalNumberOfLinesCleared)
+	}
+
+	if b.lastClear.Combo != 1 {
+		t.Errorf("Expected the second clear to continue the combo, got combo %d", b.lastClear.Combo)
+	}
+
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceO], x: 4., y: 1.}
+	b.Fall()
+
+	if b.combo != -1 {
+		t.Errorf("Expected a lock without a clear to end the combo, got %d", b.combo)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// attractDelayTicks is how long the title screen waits for input before the
+// bot starts playing a demo.
+const attractDelayTicks = 15 * ticksPerSecond
+
+// attractScene lets a bot play an endless marathon until a key is pressed.
+type attractScene struct {
+	mode  Mode
+	board *Board
+	bot   *BotInput
+}
+
+func newAttractScene(bot *BotInput) *attractScene {
+	s := &attractScene{
+		mode: MarathonMode{},
+		bot:  bot,
+	}
+	s.newGame()
+
+	return s
+}
+
+func (s *attractScene) newGame() {
+	s.board = NewBoard(rows, cols)
+	s.mode.Setup(s.board)
+	s.bot.Reset()
+}
+
+func (s *attractScene) Update(g *Game) error {
+	if g.inputHandler.MenuAction() != menuNone || len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		g.popScene()
+		return nil
+	}
+
+	if s.board.gameOver {
+		s.newGame()
+	}
+	if s.board.listener == nil {
+		g.renderer.Watch(s.board)
+	}
+
+	s.bot.Update(s.board)
+	s.board.Tick()
+	g.renderer.UpdateAnimations()
+
+	return nil
+}
+
+func (s *attractScene) Draw(g *Game, screen *ebiten.Image) {
+	g.renderer.Draw(screen, s.board, s.mode.HUD(s.board))
+	g.renderer.drawText(screen, "DEMO - PRESS ANY KEY", 10, float64(screen.Bounds().Dy())-14, 10)
+}
@@ -3,19 +3,10 @@ package main
 import (
 	"image/color"
 	"math/rand"
+	"slices"
+	"time"
 )
 
-type Field [][]color.Color
-
-func createField(rows int, cols int) Field{
-	matrix := make(Field, rows)
-	for i := range matrix {
-		matrix[i] = make([]color.Color, cols)
-	}
-
-	return matrix
-}
-
 type Tile struct{
 	x int
 	y int
@@ -23,6 +14,7 @@ type Tile struct{
 }
 	
 type Piece struct{
+	kind PieceKind
 	data [][]Tile
 }
 
@@ -47,24 +39,57 @@ func (fp *FallingPiece) getTiles() []Tile {
 type Board struct {
 	paused         bool
 	gameOver       bool
+	finished       bool // the mode's goal has been reached
+	countdown      int  // ticks left before the game starts
 	tickNumber     int
+	ticksPlayed    int
+	piecesPlaced   int
+	keyPresses     int // inputs used for the current piece
+	finesse        FinesseStats
+	stats          Stats
 	Score          int
 	Level          int
 	totalNumberOfLinesCleared int
-	linesCleared   int
+	startLevel     int
+	levelFrozen    bool // the level never goes up, e.g. in Ultra
+	garbage        GarbageSettings
+	garbageRand    *rand.Rand
+	garbageHole    int
+	pendingGarbage int
+	outgoingGarbage int // attack lines waiting to be sent to an opponent
+	combo          int
+	backToBack     bool
+	lastMoveRotate bool // for T-spin detection
+	lastClear      ClearInfo
+	garbageRefill  int // keep at least this many garbage rows in the field
+	garbageCleared int
 	field          Field
+	lockedRows     []uint16 // the field after the last lock, before garbage rose
 	currentPiece   *FallingPiece
 	pieceQueue     []*FallingPiece
 	tiles          []Piece
+	seed           int64
+	rand           *rand.Rand
+	listener       func(BoardEvent)
 }
 
 func NewBoard(rows int, cols int) *Board {
+	return NewBoardWithSeed(rows, cols, rand.Int63())
+}
+
+// NewBoardWithSeed creates a board whose pieces and garbage holes only
+// depend on the seed, so boards with the same seed get the same pieces.
+func NewBoardWithSeed(rows int, cols int, seed int64) *Board {
 	b := &Board{
 		Level:          0,
-		linesCleared:   0,
 		field:          createField(rows, cols),
 		tiles:          buildTiles(),
+		seed:           seed,
+		rand:           rand.New(rand.NewSource(seed)),
+		garbageRand:    rand.New(rand.NewSource(seed + 1)),
+		combo:          -1,
 	}
+	b.garbageHole = b.garbageRand.Intn(cols)
 
 	b.pieceQueue = []*FallingPiece{
 		b.generateRandomPiece(),
@@ -82,11 +107,17 @@ func (b *Board) TogglePause() {
 }
 
 func (b *Board) Tick() {
+	if b.countdown > 0 && !b.paused {
+		b.countdown--
+		return
+	}
+
 	if b.isStopped() {
 		return
 	}
 
 	b.tickNumber++
+	b.ticksPlayed++
 
 	b.nextLevelIfNeeded()
 
@@ -95,6 +126,11 @@ func (b *Board) Tick() {
 	}
 }
 
+// Duration returns how long the game has been played, not counting pauses.
+func (b *Board) Duration() time.Duration {
+	return time.Duration(b.ticksPlayed) * time.Second / ticksPerSecond
+}
+
 func (b *Board) MoveRight() {
 	if b.isStopped() {
 		return
@@ -102,6 +138,7 @@ func (b *Board) MoveRight() {
 
 	if !b.checkCollision(b.currentPiece, 1, 0) {
 		b.currentPiece.x += 1.0
+		b.lastMoveRotate = false
 	}
 }
 
@@ -113,6 +150,7 @@ func (b *Board) MoveLeft() {
 	// TODO There is a bug here when moving a block under another block. I don't know how to reproduce it yet.
 	if !b.checkCollision(b.currentPiece, -1, 0) {
 		b.currentPiece.x -= 1.0
+		b.lastMoveRotate = false
 	}
 }
 
@@ -129,6 +167,7 @@ func (b *Board) MoveDown() {
 	}
 
 	b.currentPiece.y += 1.0
+	b.lastMoveRotate = false
 }
 
 func (b *Board) Fall() {
@@ -136,8 +175,20 @@ func (b *Board) Fall() {
 		return
 	}
 
+	start := b.currentPiece.y
 	for !b.checkCollision(b.currentPiece, 0, 1) {
 		b.currentPiece.y += 1.0
+		b.lastMoveRotate = false
+	}
+
+	if b.listener != nil {
+		b.emit(BoardEvent{
+			Kind:     EventHardDrop,
+			Cells:    fieldCells(b.currentPiece),
+			Color:    b.currentPiece.getTiles()[0].color,
+			Piece:    int(b.currentPiece.piece.kind),
+			Distance: int(b.currentPiece.y - start),
+		})
 	}
 
 	b.addCurrentPieceToTheBoard()
@@ -175,30 +226,45 @@ func (b *Board) Rotate() {
 	}
 
 	b.currentPiece = &rotated
+	b.lastMoveRotate = true
 }
 
 func (b *Board) isStopped() bool {
-	return b.paused || b.gameOver
+	return b.paused || b.gameOver || b.finished || b.countdown > 0
+}
+
+// countKeyPress records a move or rotate key press for finesse tracking.
+// Auto-repeated moves of a held key are not key presses.
+func (b *Board) countKeyPress() {
+	b.keyPresses++
+}
+
+// PiecesPerSecond returns the average number of pieces placed per second.
+func (b *Board) PiecesPerSecond() float64 {
+	return b.stats.PiecesPerSecond(b.ticksPlayed)
 }
 
 func (b *Board) newPiece() *FallingPiece {
 	piece := b.pieceQueue[0]
+	b.keyPresses = 0
+	b.lastMoveRotate = false
 
 	b.pieceQueue = b.pieceQueue[1:]
 	b.pieceQueue = append(b.pieceQueue, b.generateRandomPiece())
 
 	if b.checkCollision(piece, 0, 0) {
 		b.gameOver = true
+		b.emit(BoardEvent{Kind: EventTopOut})
 	}
 
 	return piece
 }
 
 func (b *Board) generateRandomPiece() *FallingPiece {
-	id := rand.Intn(len(b.tiles))
+	id := b.rand.Intn(len(b.tiles))
 	piece := &FallingPiece{
 		piece: b.tiles[id],
-		x: 4.,
+		x: spawnX,
 		y: 1.,
 	}
 	
@@ -206,64 +272,64 @@ func (b *Board) generateRandomPiece() *FallingPiece {
 }
 
 func (b *Board) checkCollision(p *FallingPiece, xOffset, yOffset float64) bool {
-    for _, tile := range p.getTiles() {
-        newX := int(p.x+xOffset) + tile.x
-        newY := int(p.y+yOffset) + tile.y
-
-        if newX < 0 || newX >= cols || newY >= rows {
-            return true
-        }
-
-        if newY >= 0 && b.field[newY][newX] != nil {
-            return true
-        }
-    }
-    return false
+	return b.field.collides(p.getTiles(), int(p.x+xOffset), int(p.y+yOffset))
 }
 
 func (b *Board) addCurrentPieceToTheBoard() {
+	b.piecesPlaced++
+	b.finesse.record(b.currentPiece, b.keyPresses)
+
 	// Add to board
 	for _, tile := range b.currentPiece.getTiles() {
-		newY := int(b.currentPiece.y) + tile.y
-		b.field[newY][int(b.currentPiece.x)+tile.x] = tile.color
+		b.field.Set(int(b.currentPiece.x)+tile.x, int(b.currentPiece.y)+tile.y, tile.color, int(b.currentPiece.piece.kind))
+	}
+	tSpin := b.isTSpin()
+
+	var cleared BoardEvent
+	if b.listener != nil {
+		b.emit(BoardEvent{
+			Kind:  EventLock,
+			Cells: fieldCells(b.currentPiece),
+			Color: b.currentPiece.getTiles()[0].color,
+			Piece: int(b.currentPiece.piece.kind),
+		})
+		cleared.Kind = EventClear
 	}
 
 	// Clean full lines and count them
-	clearedCount := 0
-	writeRow := rows - 1
-	for readRow := rows - 1; readRow >= 0; readRow-- {
-		isFull := true
-		for x := 0; x < cols; x++ {
-			if b.field[readRow][x] == nil {
-				isFull = false
-				break
-			}
+	clearedCount := b.field.clearFullRows(func(y int) {
+		if b.field.isGarbageRow(y) {
+			b.garbageCleared++
 		}
-
-		if (isFull) {
-			clearedCount++
-		} else {
-			if readRow != writeRow {
-				b.field[writeRow] = b.field[readRow]
-			}
-			writeRow--
+		if b.listener != nil {
+			cleared.Rows = append(cleared.Rows, y)
+			cleared.RowColors = append(cleared.RowColors, slices.Clone(b.field.colors[y]))
+			cleared.RowKinds = append(cleared.RowKinds, b.field.RowKinds(y))
 		}
-	}
+	})
+	b.lockedRows = append(b.lockedRows[:0], b.field.rows...)
 
 	if clearedCount > 0 {
+		b.emit(cleared)
 		b.totalNumberOfLinesCleared += clearedCount
 		b.addScore(clearedCount)
-		b.linesCleared += clearedCount
-		// Level up every 10 lines
-		if b.linesCleared >= 10 {
-			b.Level++
-			b.linesCleared -= 10
-		}
+		b.nextLevelIfNeeded()
+	}
+
+	b.recordClear(clearedCount, tSpin)
+
+	attack := 0
+	if clearedCount > 0 {
+		attack = b.lastClear.Attack()
 	}
+	b.stats.record(b.currentPiece.piece.kind, b.keyPresses, clearedCount, attack)
 
-	// Fill the cleared lines at the top with new empty rows
-	for y := writeRow; y >= 0; y-- {
-		b.field[y] = make([]color.Color, cols)
+	if clearedCount == 0 {
+		b.raisePendingGarbage()
+	}
+
+	if b.garbageRefill > 0 {
+		b.raiseGarbage(b.garbageRefill - b.GarbageRowsLeft())
 	}
 }
 
@@ -286,8 +352,28 @@ func (b *Board) addScore(lines int) {
 	b.Score += baseScores[scoreIndex] * (b.Level + 1)
 }
 
+// SetStartLevel starts the game at a higher level.
+func (b *Board) SetStartLevel(level int) {
+	b.startLevel = level
+	b.Level = level
+}
+
 func (b *Board) nextLevelIfNeeded() {
-	for b.totalNumberOfLinesCleared >= (b.Level+1)*10 {
+	if b.levelFrozen {
+		return
+	}
+
+	for b.totalNumberOfLinesCleared >= b.linesForNextLevel() {
 		b.Level++
+		b.stats.LevelTicks = append(b.stats.LevelTicks, b.ticksPlayed)
+		b.emit(BoardEvent{Kind: EventLevelUp, Level: b.Level})
 	}
 }
+
+// linesForNextLevel follows the NES rules: the first level up from a high
+// start level needs more lines (at most 100 more than from level 0), and
+// after that the level goes up every 10 lines.
+func (b *Board) linesForNextLevel() int {
+	first := min(b.startLevel*10+10, max(100, b.startLevel*10-50))
+	return first + (b.Level-b.startLevel)*10
+}
@@ -89,10 +89,201 @@ func fillBoardFromString(board *Board, layout string) {
 			for c, char := range line {
 				if c < cols {
 					if char == 'x' {
-						board.field[r][c] = color.White
+						board.field.Set(c, r, color.White, garbageKind)
 					}
 				}
 			}
 		}
 	}
 }
+
+func TestFinesseFaults(t *testing.T) {
+	b := NewBoard(rows, cols)
+
+	// T piece moved one column right with a single tap: no fault
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceT], x: spawnX, y: 1.}
+	b.countKeyPress()
+	b.MoveRight()
+	b.Fall()
+
+	if b.finesse.Faults != 0 {
+		t.Errorf("Expected no finesse faults, got %d", b.finesse.Faults)
+	}
+
+	// Same placement reached with a wasted left/right tap
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceT], x: spawnX, y: 1.}
+	b.keyPresses = 0
+	for range 3 {
+		b.countKeyPress()
+	}
+	b.MoveLeft()
+	b.MoveRight()
+	b.MoveRight()
+	b.Fall()
+
+	if b.finesse.Faults != 1 {
+		t.Errorf("Expected 1 finesse fault, got %d", b.finesse.Faults)
+	}
+
+	if b.piecesPlaced != 2 {
+		t.Errorf("Expected 2 pieces placed, got %d", b.piecesPlaced)
+	}
+}
+
+func TestMinimumKeyPresses(t *testing.T) {
+	tiles := buildTiles()
+	tests := []struct {
+		name      string
+		piece     FallingPiece
+		taps, das int
+	}{
+		{name: "T at spawn", piece: FallingPiece{piece: tiles[PieceT], x: spawnX}, taps: 0, das: 0},
+		{name: "T to the left wall", piece: FallingPiece{piece: tiles[PieceT], x: 1}, taps: 3, das: 1},
+		{name: "T one from the left wall", piece: FallingPiece{piece: tiles[PieceT], x: 2}, taps: 2, das: 2},
+		{name: "O to the right wall", piece: FallingPiece{piece: tiles[PieceO], x: 8}, taps: 4, das: 1},
+		{name: "flat I to the right wall", piece: FallingPiece{piece: tiles[PieceI], state: 1, x: 7}, taps: 4, das: 2},
+		{name: "T rotated three times", piece: FallingPiece{piece: tiles[PieceT], state: 3, x: spawnX}, taps: 3, das: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minimumKeyPresses(&tt.piece); got != tt.taps {
+				t.Errorf("Expected %d presses tapping, got %d", tt.taps, got)
+			}
+			if got := minimumKeyPressesDAS(&tt.piece); got != tt.das {
+				t.Errorf("Expected %d presses with DAS, got %d", tt.das, got)
+			}
+		})
+	}
+}
+
+func TestFinesse_TappingToTheWall(t *testing.T) {
+	b := NewBoard(rows, cols)
+
+	// Three taps take the T piece to the wall, where one long press would do
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceT], x: spawnX, y: 1.}
+	for range 3 {
+		b.countKeyPress()
+		b.MoveLeft()
+	}
+	b.Fall()
+
+	if b.finesse.Faults != 1 || b.finesse.TapFaults != 0 {
+		t.Errorf("Expected 1 fault and no tap faults, got %d and %d", b.finesse.Faults, b.finesse.TapFaults)
+	}
+
+	if p := b.finesse.Pieces[PieceT]; p != (PieceFinesse{Placed: 1, Faults: 1, Wasted: 2}) {
+		t.Errorf("Unexpected T piece breakdown %+v", p)
+	}
+}
+
+func TestSprintMode_FinishesAtLineGoal(t *testing.T) {
+	b := NewBoard(rows, cols)
+	mode := SprintMode{Lines: 40}
+	mode.Setup(b)
+
+	if !b.isStopped() {
+		t.Fatalf("Expected the board to wait for the countdown")
+	}
+
+	for range countdownTicks {
+		b.Tick()
+	}
+
+	if b.isStopped() {
+		t.Fatalf("Expected the board to run after the countdown")
+	}
+
+	b.totalNumberOfLinesCleared = 39
+	if mode.Finished(b) {
+		t.Errorf("Expected the sprint not to be finished at 39 lines")
+	}
+
+	b.totalNumberOfLinesCleared = 40
+	if !mode.Finished(b) {
+		t.Errorf("Expected the sprint to be finished at 40 lines")
+	}
+}
+
+func TestUltraMode_ClockStopsWhilePaused(t *testing.T) {
+	b := NewBoard(rows, cols)
+	mode := UltraMode{Seconds: 1}
+	mode.Setup(b)
+
+	for range countdownTicks + ticksPerSecond/2 {
+		b.Tick()
+	}
+
+	b.TogglePause()
+	for range ticksPerSecond {
+		b.Tick()
+	}
+
+	if mode.Finished(b) {
+		t.Fatalf("Expected the clock to stop while paused, but the game finished")
+	}
+
+	b.TogglePause()
+	for range ticksPerSecond / 2 {
+		b.Tick()
+	}
+
+	if !mode.Finished(b) {
+		t.Errorf("Expected the game to finish after %d ticks, played %d", ticksPerSecond, b.ticksPlayed)
+	}
+
+	if b.Level != 0 {
+		t.Errorf("Expected the level to stay frozen at 0, got %d", b.Level)
+	}
+}
+
+func TestLevelUp_HighStartLevel(t *testing.T) {
+	tests := []struct {
+		startLevel int
+		firstLevel int // lines needed for the first level up
+	}{
+		{startLevel: 0, firstLevel: 10},
+		{startLevel: 5, firstLevel: 60},
+		{startLevel: 9, firstLevel: 100},
+		{startLevel: 15, firstLevel: 100},
+		{startLevel: 19, firstLevel: 140},
+	}
+
+	for _, tt := range tests {
+		b := NewBoard(rows, cols)
+		b.SetStartLevel(tt.startLevel)
+
+		b.totalNumberOfLinesCleared = tt.firstLevel - 1
+		b.nextLevelIfNeeded()
+		if b.Level != tt.startLevel {
+			t.Errorf("Start level %d: expected no level up at %d lines, got level %d", tt.startLevel, b.totalNumberOfLinesCleared, b.Level)
+		}
+
+		b.totalNumberOfLinesCleared = tt.firstLevel
+		b.nextLevelIfNeeded()
+		if b.Level != tt.startLevel+1 {
+			t.Errorf("Start level %d: expected level %d at %d lines, got %d", tt.startLevel, tt.startLevel+1, b.totalNumberOfLinesCleared, b.Level)
+		}
+
+		b.totalNumberOfLinesCleared = tt.firstLevel + 10
+		b.nextLevelIfNeeded()
+		if b.Level != tt.startLevel+2 {
+			t.Errorf("Start level %d: expected level %d at %d lines, got %d", tt.startLevel, tt.startLevel+2, b.totalNumberOfLinesCleared, b.Level)
+		}
+	}
+}
+
+func TestMarathonGoal_MaxStartLevel(t *testing.T) {
+	for _, goal := range marathonGoals {
+		b := NewBoard(rows, cols)
+		mode := MarathonMode{Goal: goal, StartLevel: goal.MaxStartLevel()}
+		mode.Setup(b)
+		if mode.Finished(b) {
+			t.Errorf("Goal %v: expected a game from start level %d not to be finished at once", goal, mode.StartLevel)
+		}
+	}
+
+	if got := (MarathonGoal{Level: 15}).MaxStartLevel(); got != 14 {
+		t.Errorf("Expected level 15 games to start at most at level 14, got %d", got)
+	}
+}
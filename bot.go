@@ -0,0 +1,337 @@
+package main
+
+import "math/bits"
+
+// BotWeights weigh the board features the bot uses to rate a placement,
+// following Pierre Dellacherie's heuristic as tuned by El-Tetris.
+type BotWeights struct {
+	LandingHeight     float64 `json:"landing_height"`
+	ErodedCells       float64 `json:"eroded_cells"`
+	RowTransitions    float64 `json:"row_transitions"`
+	ColumnTransitions float64 `json:"column_transitions"`
+	Holes             float64 `json:"holes"`
+	Wells             float64 `json:"wells"`
+}
+
+var defaultBotWeights = BotWeights{
+	LandingHeight:     -4.500158825082766,
+	ErodedCells:       3.4181268101392694,
+	RowTransitions:    -3.2178882868487753,
+	ColumnTransitions: -9.348695305445199,
+	Holes:             -7.899265427351652,
+	Wells:             -3.3855972247263626,
+}
+
+// Placement is where the current piece ends up after rotating it, moving it
+// sideways and hard dropping it.
+type Placement struct {
+	State int // orientation after rotating
+	X     int // column after moving
+	piece FallingPiece
+	Score float64
+}
+
+// Placements lists every placement of the current piece that can be reached
+// by rotating it first, then moving it left or right, then hard dropping.
+func (b *Board) Placements() []Placement {
+	if b.currentPiece == nil || b.gameOver || b.finished {
+		return nil
+	}
+
+	// The moves only change the copy's falling piece, never the field
+	sim := *b
+	sim.paused = false
+	sim.countdown = 0
+
+	placements := make([]Placement, 0, 4*cols)
+	// Columns are offset by 2, as pieces can reach past the left wall
+	var seen [4][cols + 4]bool
+
+	for rotations := range len(b.currentPiece.piece.data) {
+		start := *b.currentPiece
+		sim.currentPiece = &start
+		for range rotations {
+			sim.Rotate()
+		}
+		rotated := *sim.currentPiece
+
+		for _, move := range []func(){sim.MoveLeft, sim.MoveRight} {
+			p := rotated
+			sim.currentPiece = &p
+
+			for {
+				if !seen[p.state][int(p.x)+2] {
+					seen[p.state][int(p.x)+2] = true
+					placements = append(placements, Placement{State: p.state, X: int(p.x), piece: b.dropPosition(p)})
+				}
+
+				x := p.x
+				move()
+				if sim.currentPiece.x == x {
+					break
+				}
+				p = *sim.currentPiece
+			}
+		}
+	}
+
+	return placements
+}
+
+// dropPosition returns where a piece lands when it is hard dropped.
+func (b *Board) dropPosition(p FallingPiece) FallingPiece {
+	for !b.checkCollision(&p, 0, 1) {
+		p.y += 1.0
+	}
+
+	return p
+}
+
+// BestPlacement rates every placement of the current piece and returns the
+// best one.
+func (b *Board) BestPlacement(w BotWeights) (Placement, bool) {
+	placements := b.Placements()
+	if len(placements) == 0 {
+		return Placement{}, false
+	}
+
+	scratch := make([]uint16, b.field.Height())
+	best := 0
+	for i := range placements {
+		placements[i].Score = b.ratePlacement(&placements[i].piece, w, scratch)
+		if placements[i].Score > placements[best].Score {
+			best = i
+		}
+	}
+
+	return placements[best], true
+}
+
+// ratePlacement locks the piece into a copy of the field and rates the
+// result. The copy is made in scratch when it is big enough.
+func (b *Board) ratePlacement(p *FallingPiece, w BotWeights, scratch []uint16) float64 {
+	filled, cleared, pieceCells := b.fieldAfter(p, scratch)
+	width := b.field.Width()
+
+	top, bottom := len(filled), 0
+	for _, tile := range p.getTiles() {
+		top = min(top, int(p.y)+tile.y)
+		bottom = max(bottom, int(p.y)+tile.y)
+	}
+	landingHeight := float64(len(filled)) - float64(top+bottom)/2
+
+	return w.LandingHeight*landingHeight +
+		w.ErodedCells*float64(cleared*pieceCells) +
+		w.RowTransitions*float64(rowTransitions(filled, width)) +
+		w.ColumnTransitions*float64(columnTransitions(filled, width)) +
+		w.Holes*float64(holes(filled)) +
+		w.Wells*float64(wellSums(filled, width))
+}
+
+// fieldAfter returns the row masks of the field once the piece locks and
+// the full rows are cleared, along with the number of cleared rows and the
+// cells of the piece they took away. The masks are written to dst when it
+// is big enough.
+func (b *Board) fieldAfter(p *FallingPiece, dst []uint16) (filled []uint16, cleared, pieceCells int) {
+	height := b.field.Height()
+	if cap(dst) < height {
+		dst = make([]uint16, height)
+	}
+	filled = dst[:height]
+	copy(filled, b.field.rows)
+
+	for _, tile := range p.getTiles() {
+		x, y := int(p.x)+tile.x, int(p.y)+tile.y
+		if y >= 0 {
+			filled[y] |= 1 << x
+		}
+	}
+
+	// Clear full rows from the bottom up, counting the cells of the piece
+	// they take away
+	write := height - 1
+	for y := height - 1; y >= 0; y-- {
+		if filled[y] != b.field.full {
+			filled[write] = filled[y]
+			write--
+			continue
+		}
+
+		cleared++
+		for _, tile := range p.getTiles() {
+			if int(p.y)+tile.y == y {
+				pieceCells++
+			}
+		}
+	}
+	clear(filled[:write+1])
+
+	return filled, cleared, pieceCells
+}
+
+// rowTransitions counts the changes between filled and empty cells along
+// each row. The walls count as filled.
+func rowTransitions(filled []uint16, width int) int {
+	walls := uint32(1) | 1<<(width+1)
+	pairs := uint32(1)<<(width+1) - 1
+
+	transitions := 0
+	for _, row := range filled {
+		v := uint32(row)<<1 | walls
+		transitions += bits.OnesCount32((v ^ v>>1) & pairs)
+	}
+
+	return transitions
+}
+
+// columnTransitions counts the changes between filled and empty cells down
+// each column. The floor counts as filled.
+func columnTransitions(filled []uint16, width int) int {
+	full := uint16(1<<width - 1)
+
+	transitions := 0
+	previous := uint16(0)
+	for _, row := range filled {
+		transitions += bits.OnesCount16(row ^ previous)
+		previous = row
+	}
+
+	return transitions + bits.OnesCount16(^previous&full)
+}
+
+// holes counts the empty cells with a filled cell somewhere above them.
+func holes(filled []uint16) int {
+	count := 0
+	covered := uint16(0)
+	for _, row := range filled {
+		count += bits.OnesCount16(covered &^ row)
+		covered |= row
+	}
+
+	return count
+}
+
+// wellSums adds up the depth of every well cell, an empty cell whose left and
+// right neighbours are filled, so a well of depth n counts 1+2+...+n.
+func wellSums(filled []uint16, width int) int {
+	full := uint16(1<<width - 1)
+	var depth [16]int
+
+	sum := 0
+	for _, row := range filled {
+		// The walls count as filled neighbours
+		left := row<<1 | 1
+		right := row>>1 | 1<<(width-1)
+		wells := ^row & left & right & full
+
+		for x := range width {
+			if wells&(1<<x) != 0 {
+				depth[x]++
+				sum += depth[x]
+			} else {
+				depth[x] = 0
+			}
+		}
+	}
+
+	return sum
+}
+
+// BotInput plays a board like a player would: it picks a placement for each
+// new piece and presses the buttons to get there, one press at a time. It
+// works like an InputHandler, so the bot can play any game a player can.
+type BotInput struct {
+	settings   *Settings
+	controller *Controller
+	// choose picks the placement of the current piece. It may return false
+	// while it's still thinking; it is asked again on the next tick.
+	choose func(b *Board) (Placement, bool)
+
+	placed    int // piecesPlaced when the current piece spawned
+	planned   bool
+	target    Placement
+	rotations int // rotate presses used on the current piece
+	wait      int // ticks until the next press
+	pressed   Buttons
+}
+
+// NewBotInput creates a bot that plays with the built-in heuristic.
+func NewBotInput(settings *Settings, weights BotWeights) *BotInput {
+	return newBotInput(settings, func(b *Board) (Placement, bool) {
+		return b.BestPlacement(weights)
+	})
+}
+
+func newBotInput(settings *Settings, choose func(b *Board) (Placement, bool)) *BotInput {
+	return &BotInput{
+		settings:   settings,
+		controller: NewController(settings),
+		choose:     choose,
+		placed:     -1,
+	}
+}
+
+// Update should be called every tick, like InputHandler.Update.
+func (i *BotInput) Update(board *Board) {
+	i.controller.Apply(board, i.Buttons(board))
+}
+
+func (i *BotInput) Reset() {
+	i.controller.Reset()
+	i.placed = -1
+	i.wait = 0
+	i.pressed = 0
+}
+
+// Buttons returns the buttons the bot holds down this tick. Every press is
+// released on the next tick so that it isn't auto-repeated.
+func (i *BotInput) Buttons(b *Board) Buttons {
+	if b.isStopped() {
+		return 0
+	}
+
+	if i.pressed != 0 {
+		i.pressed = 0
+		return 0
+	}
+
+	if i.placed != b.piecesPlaced {
+		i.placed = b.piecesPlaced
+		i.planned = false
+		i.rotations = 0
+		i.wait = i.settings.BotDelay()
+	}
+
+	if !i.planned {
+		if i.target, i.planned = i.choose(b); !i.planned {
+			return 0
+		}
+	}
+
+	if i.wait > 0 {
+		i.wait--
+		return 0
+	}
+
+	i.pressed = i.nextPress(b)
+	i.wait = i.settings.BotDelay()
+
+	return i.pressed
+}
+
+func (i *BotInput) nextPress(b *Board) Buttons {
+	p := b.currentPiece
+
+	switch {
+	// A rotation can be blocked, so give up after trying every orientation
+	case p.state != i.target.State && i.rotations < len(p.piece.data):
+		i.rotations++
+		return ButtonRotate
+	case int(p.x) > i.target.X:
+		return ButtonLeft
+	case int(p.x) < i.target.X:
+		return ButtonRight
+	}
+
+	return ButtonHardDrop
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestPlacements_ReachEveryColumn(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceO], x: spawnX, y: 1}
+
+	placements := b.Placements()
+	if len(placements) != cols-1 {
+		t.Fatalf("O piece has %d placements, want %d", len(placements), cols-1)
+	}
+
+	for _, p := range placements {
+		if int(p.piece.y) != rows-2 {
+			t.Errorf("O piece in column %d lands on row %d, want %d", p.X, int(p.piece.y), rows-2)
+		}
+	}
+
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceI], x: spawnX, y: 1}
+	// 10 columns standing up, 7 lying down
+	if n := len(b.Placements()); n != 17 {
+		t.Errorf("I piece has %d placements, want 17", n)
+	}
+}
+
+func TestBoardFeatures(t *testing.T) {
+	// ....
+	// .x..
+	// x..x
+	// xx.x
+	filled := []uint16{0b0000, 0b0010, 0b1001, 0b1011}
+	width := 4
+
+	if got := holes(filled); got != 1 {
+		t.Errorf("holes = %d, want 1", got)
+	}
+	// 2 for the empty row, 4, 2 and 2
+	if got := rowTransitions(filled, width); got != 10 {
+		t.Errorf("row transitions = %d, want 10", got)
+	}
+	// 1, 3, 1 for the floor and 1
+	if got := columnTransitions(filled, width); got != 6 {
+		t.Errorf("column transitions = %d, want 6", got)
+	}
+	// Next to the wall in column 0 and at the bottom of column 2
+	if got := wellSums(filled, width); got != 2 {
+		t.Errorf("well sums = %d, want 2", got)
+	}
+}
+
+func TestBestPlacement_FillsTheGap(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	for y := rows - 4; y < rows; y++ {
+		for x := range cols {
+			if x != 4 {
+				b.field.Set(x, y, colorGarbage, garbageKind)
+			}
+		}
+	}
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceI], x: spawnX, y: 1}
+
+	best, ok := b.BestPlacement(defaultBotWeights)
+	if !ok {
+		t.Fatal("no placement found")
+	}
+	if best.X != 4 || best.State != 0 {
+		t.Errorf("best placement is column %d state %d, want the I piece standing in column 4", best.X, best.State)
+	}
+}
+
+func TestBotInput_PlaysTheGame(t *testing.T) {
+	settings := DefaultSettings()
+	settings.BotSpeed = maxBotSpeed
+	bot := NewBotInput(settings, defaultBotWeights)
+	b := NewBoardWithSeed(rows, cols, 7)
+
+	// Long enough for a few hundred pieces, before the gravity gets too fast
+	for range 3000 {
+		bot.Update(b)
+		b.Tick()
+		if b.gameOver {
+			break
+		}
+	}
+
+	if b.gameOver {
+		t.Errorf("bot topped out after %d pieces", b.piecesPlaced)
+	}
+	if b.totalNumberOfLinesCleared < 20 {
+		t.Errorf("bot cleared only %d lines with %d pieces", b.totalNumberOfLinesCleared, b.piecesPlaced)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import "slices"
+
+// CoachLevel sets how often the coach shows a hint.
+type CoachLevel int
+
+const (
+	CoachOff CoachLevel = iota
+	// CoachEasy hints every piece.
+	CoachEasy
+	// CoachMedium hints every other piece.
+	CoachMedium
+	// CoachHard only hints after a poor placement.
+	CoachHard
+)
+
+var coachLevelNames = []string{"Off", "Easy", "Medium", "Hard"}
+
+func (l CoachLevel) String() string {
+	return coachLevelNames[l]
+}
+
+// PlacementRating tells the player how good their last placement was.
+type PlacementRating struct {
+	Grade string
+	// Holes and Bumpiness are what the placement added to the field.
+	Holes     int
+	Bumpiness int
+}
+
+const (
+	gradeBest  = "BEST"
+	gradeGood  = "GOOD"
+	gradeBumpy = "BUMPY"
+	gradePoor  = "HOLE"
+)
+
+// Coach suggests where to put each piece, using the bot's search, and rates
+// where the player put it after it locks.
+type Coach struct {
+	level   CoachLevel
+	weights BotWeights
+
+	placed   int      // piecesPlaced when the current piece spawned
+	before   []uint16 // the field when the current piece spawned
+	best     []uint16 // the field after the suggested placement
+	hint     *FallingPiece
+	showHint bool
+
+	// Rating is the rating of the last placement, nil before the first lock.
+	Rating *PlacementRating
+}
+
+func NewCoach(level CoachLevel, weights BotWeights) *Coach {
+	return &Coach{level: level, weights: weights, placed: -1}
+}
+
+// Update should be called every tick after the board has ticked.
+func (c *Coach) Update(b *Board) {
+	if c.level == CoachOff || c.placed == b.piecesPlaced {
+		return
+	}
+
+	// Rated on the field the placement left, as garbage rising after the
+	// lock is not the player's doing
+	if c.placed >= 0 && c.before != nil {
+		c.Rating = c.rate(b.lockedRows, b.field.Width())
+	}
+	c.placed = b.piecesPlaced
+
+	c.before = append(c.before[:0], b.field.rows...)
+	c.hint = nil
+	if best, ok := b.BestPlacement(c.weights); ok {
+		c.hint = &best.piece
+		c.best, _, _ = b.fieldAfter(&best.piece, c.best)
+	}
+
+	switch c.level {
+	case CoachEasy:
+		c.showHint = true
+	case CoachMedium:
+		c.showHint = b.piecesPlaced%2 == 0
+	case CoachHard:
+		c.showHint = c.Rating != nil && c.Rating.Grade == gradePoor
+	}
+}
+
+// Hint returns where the coach suggests putting the current piece, or nil
+// when no hint is shown for it.
+func (c *Coach) Hint() *FallingPiece {
+	if !c.showHint {
+		return nil
+	}
+
+	return c.hint
+}
+
+func (c *Coach) rate(after []uint16, width int) *PlacementRating {
+	r := &PlacementRating{
+		Holes:     max(holes(after)-holes(c.before), 0),
+		Bumpiness: max(bumpiness(after, width)-bumpiness(c.before, width), 0),
+	}
+
+	switch {
+	case slices.Equal(after, c.best):
+		r.Grade = gradeBest
+	case r.Holes > 0:
+		r.Grade = gradePoor
+	case r.Bumpiness > 2:
+		r.Grade = gradeBumpy
+	default:
+		r.Grade = gradeGood
+	}
+
+	return r
+}
+
+// bumpiness adds up the height differences of neighbouring columns.
+func bumpiness(filled []uint16, width int) int {
+	var heights [16]int
+	seen := uint16(0)
+	for y, row := range filled {
+		for x := range width {
+			if row&^seen&(1<<x) != 0 {
+				heights[x] = len(filled) - y
+			}
+		}
+		seen |= row
+	}
+
+	sum := 0
+	for x := 1; x < width; x++ {
+		sum += max(heights[x]-heights[x-1], heights[x-1]-heights[x])
+	}
+
+	return sum
+}
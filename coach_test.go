@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCoach_RatesSuggestedPlacementBest(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	coach := NewCoach(CoachEasy, defaultBotWeights)
+	coach.Update(b)
+
+	hint := coach.Hint()
+	if hint == nil {
+		t.Fatalf("Expected a hint on the easy level")
+	}
+
+	p := *hint
+	b.currentPiece = &p
+	b.Fall()
+	coach.Update(b)
+
+	if coach.Rating == nil || coach.Rating.Grade != gradeBest {
+		t.Errorf("Expected the suggested placement to be rated %s, got %+v", gradeBest, coach.Rating)
+	}
+}
+
+func TestCoach_DigRefillIsNotRated(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	DigMode{Rows: 10, Endless: true}.Setup(b)
+	b.countdown = 0
+	coach := NewCoach(CoachEasy, defaultBotWeights)
+	coach.Update(b)
+
+	// Every garbage row cleared raises a new one with a covered hole
+	for i := 0; i < 30 && !b.gameOver; i++ {
+		p := *coach.Hint()
+		b.currentPiece = &p
+		b.Fall()
+		coach.Update(b)
+
+		if coach.Rating.Grade != gradeBest {
+			t.Fatalf("Piece %d: expected the suggested placement to be rated %s, got %+v", i, gradeBest, coach.Rating)
+		}
+	}
+
+	if b.garbageCleared == 0 {
+		t.Errorf("Expected the suggested placements to dig into the garbage")
+	}
+}
+
+func TestCoach_RatesHoles(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	b.field.Set(1, rows-1, color.White, garbageKind)
+	coach := NewCoach(CoachHard, defaultBotWeights)
+	coach.Update(b)
+
+	if coach.Hint() != nil {
+		t.Fatalf("Expected no hint on the hard level before a poor placement")
+	}
+
+	// An O piece on top of the block leaves a hole next to it
+	b.currentPiece = &FallingPiece{piece: b.tiles[1], x: 0., y: 1.}
+	b.Fall()
+	coach.Update(b)
+
+	if coach.Rating == nil || coach.Rating.Grade != gradePoor || coach.Rating.Holes != 1 {
+		t.Fatalf("Expected the placement to be rated %s with 1 hole, got %+v", gradePoor, coach.Rating)
+	}
+
+	if coach.Hint() == nil {
+		t.Errorf("Expected a hint on the hard level after a poor placement")
+	}
+}
+
+func TestCoach_Off(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	coach := NewCoach(CoachOff, defaultBotWeights)
+	coach.Update(b)
+	b.Fall()
+	coach.Update(b)
+
+	if coach.Hint() != nil || coach.Rating != nil {
+		t.Errorf("Expected no hints or ratings with the coach off")
+	}
+}
+
+func TestBumpiness(t *testing.T) {
+	filled := make([]uint16, 4)
+	filled[2] = 0b0001
+	filled[3] = 0b1011
+
+	// Heights 2, 1, 0, 1
+	if got := bumpiness(filled, 4); got != 3 {
+		t.Errorf("Expected bumpiness 3, got %d", got)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+// Buttons is the set of game buttons held down during a tick.
+type Buttons uint8
+
+const (
+	ButtonLeft Buttons = 1 << iota
+	ButtonRight
+	ButtonSoftDrop
+	ButtonRotate
+	ButtonHardDrop
+
+	buttonCount = 5
+)
+
+// Controller turns the buttons held each tick into board actions. Moves and
+// soft drop repeat while held, using the DAS and ARR from the settings; the
+// other buttons act once per press. It doesn't know about input devices, so
+// keyboards, gamepads and bots can all drive a board through it.
+type Controller struct {
+	settings *Settings
+	held     [buttonCount]int // ticks each button has been held down
+	started  bool
+	ignored  Buttons
+}
+
+func NewController(settings *Settings) *Controller {
+	return &Controller{settings: settings}
+}
+
+// Reset forgets the held buttons, e.g. when a new game starts.
+func (c *Controller) Reset() {
+	c.held = [buttonCount]int{}
+	c.started = false
+}
+
+func (c *Controller) Apply(board *Board, buttons Buttons) {
+	// Buttons held when the controller starts were pressed for something
+	// else, like confirming a menu, and are ignored until released.
+	if !c.started {
+		c.started = true
+		c.ignored = buttons
+	}
+	c.ignored &= buttons
+	buttons &^= c.ignored
+
+	for i := range c.held {
+		if buttons&(1<<i) != 0 {
+			c.held[i]++
+		} else {
+			c.held[i] = 0
+		}
+	}
+
+	if board == nil || board.isStopped() {
+		return
+	}
+
+	// Buttons pressed in the same tick are a key press each
+	for _, button := range []Buttons{ButtonLeft, ButtonRight, ButtonRotate} {
+		if c.justPressed(button) {
+			board.countKeyPress()
+		}
+	}
+
+	if c.pressAndMove(ButtonLeft) {
+		board.MoveLeft()
+	}
+
+	if c.pressAndMove(ButtonRight) {
+		board.MoveRight()
+	}
+
+	if c.pressAndMove(ButtonSoftDrop) {
+		board.MoveDown()
+	}
+
+	if c.justPressed(ButtonHardDrop) {
+		board.Fall()
+	}
+
+	if c.justPressed(ButtonRotate) {
+		board.Rotate()
+	}
+}
+
+func (c *Controller) duration(button Buttons) int {
+	for i := range c.held {
+		if button == 1<<i {
+			return c.held[i]
+		}
+	}
+
+	return 0
+}
+
+func (c *Controller) justPressed(button Buttons) bool {
+	return c.duration(button) == 1
+}
+
+func (c *Controller) pressAndMove(button Buttons) bool {
+	d := c.duration(button)
+	return d == 1 || (d > c.settings.DAS && d%c.settings.ARR == 0)
+}
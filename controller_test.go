@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestController_AutoRepeat(t *testing.T) {
+	settings := DefaultSettings()
+	settings.DAS = 10
+	settings.ARR = 2
+
+	b := NewBoard(rows, cols)
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceO], x: 0., y: 1.}
+	c := NewController(settings)
+	c.Apply(b, 0)
+
+	// The first tick moves, then nothing until DAS, then every ARR ticks
+	for range 14 {
+		c.Apply(b, ButtonRight)
+	}
+
+	// Moves at ticks 1, 12 and 14
+	if b.currentPiece.x != 3 {
+		t.Errorf("Expected the piece at x=3, got %v", b.currentPiece.x)
+	}
+
+	if b.keyPresses != 1 {
+		t.Errorf("Expected a held button to count as one key press, got %d", b.keyPresses)
+	}
+}
+
+func TestController_CountsButtonsPressedTogether(t *testing.T) {
+	b := NewBoard(rows, cols)
+	c := NewController(DefaultSettings())
+	c.Apply(b, 0)
+
+	c.Apply(b, ButtonLeft|ButtonRotate)
+	c.Apply(b, ButtonLeft|ButtonRotate)
+
+	if b.keyPresses != 2 {
+		t.Errorf("Expected left and rotate pressed together to be 2 key presses, got %d", b.keyPresses)
+	}
+}
+
+func TestController_IgnoresButtonsHeldAtStart(t *testing.T) {
+	b := NewBoard(rows, cols)
+	c := NewController(DefaultSettings())
+	piece := b.currentPiece
+
+	// Hard drop still held from confirming the menu
+	c.Apply(b, ButtonHardDrop)
+	c.Apply(b, ButtonHardDrop)
+
+	if b.currentPiece != piece {
+		t.Fatalf("Expected a button held at start not to hard drop")
+	}
+
+	c.Apply(b, 0)
+	c.Apply(b, ButtonHardDrop)
+
+	if b.currentPiece == piece {
+		t.Errorf("Expected a new press to hard drop")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image"
+	"image/color"
+)
+
+// BoardEventKind says what happened on a board.
+type BoardEventKind int
+
+const (
+	EventLock BoardEventKind = iota
+	EventClear
+	EventLevelUp
+	EventHardDrop
+	EventTopOut
+)
+
+// BoardEvent is sent to the board's listener as things happen, for effects
+// that don't change the game.
+type BoardEvent struct {
+	Kind BoardEventKind
+
+	// Cells are the field cells of the piece that locked or dropped, and
+	// Color and Piece its colour and kind.
+	Cells []image.Point
+	Color color.Color
+	Piece int
+
+	// Rows are the field rows that were cleared, before the rows above them
+	// fell down, and RowColors and RowKinds their cells.
+	Rows      []int
+	RowColors [][]color.Color
+	RowKinds  [][]int
+
+	Level    int // the new level
+	Distance int // rows a hard dropped piece fell
+}
+
+// SetListener makes the board call listen for every event. Boards without
+// a listener don't gather events at all.
+func (b *Board) SetListener(listen func(BoardEvent)) {
+	b.listener = listen
+}
+
+func (b *Board) emit(e BoardEvent) {
+	if b.listener != nil {
+		b.listener(e)
+	}
+}
+
+// fieldCells returns the field cells of a piece.
+func fieldCells(p *FallingPiece) []image.Point {
+	tiles := p.getTiles()
+	cells := make([]image.Point, len(tiles))
+	for i, tile := range tiles {
+		cells[i] = image.Pt(int(p.x)+tile.x, int(p.y)+tile.y)
+	}
+
+	return cells
+}
@@ -0,0 +1,187 @@
+package main
+
+import "image/color"
+
+// Field is the grid of settled blocks. Each row keeps which cells are
+// filled as a bitmask, bit x for column x, so collision and full line
+// checks are a few bitwise operations. The colours and kinds of the blocks
+// are kept apart, only for drawing. A field is at most 16 columns wide.
+type Field struct {
+	rows   []uint16
+	colors [][]color.Color
+	kinds  [][]uint8 // the kind of each block plus one, so 0 is empty
+	width  int
+	full   uint16 // the mask of a full row
+}
+
+// garbageKind is the kind of garbage blocks, after the piece kinds.
+const garbageKind = len(pieceNames)
+
+func createField(rows int, cols int) Field {
+	f := Field{
+		rows:   make([]uint16, rows),
+		colors: make([][]color.Color, rows),
+		kinds:  make([][]uint8, rows),
+		width:  cols,
+		full:   uint16(1<<cols - 1),
+	}
+
+	for y := range f.colors {
+		f.colors[y] = make([]color.Color, cols)
+		f.kinds[y] = make([]uint8, cols)
+	}
+
+	return f
+}
+
+func (f *Field) Height() int {
+	return len(f.rows)
+}
+
+func (f *Field) Width() int {
+	return f.width
+}
+
+// Filled reports whether a cell inside the field holds a block.
+func (f *Field) Filled(x, y int) bool {
+	return f.rows[y]&(1<<x) != 0
+}
+
+// Color returns the colour of the block in a cell, nil for an empty cell.
+func (f *Field) Color(x, y int) color.Color {
+	return f.colors[y][x]
+}
+
+// Kind returns the PieceKind of the block in a cell, garbageKind for
+// garbage and -1 for an empty cell.
+func (f *Field) Kind(x, y int) int {
+	return int(f.kinds[y][x]) - 1
+}
+
+// RowKinds returns the kinds of the cells of a row, as Kind does.
+func (f *Field) RowKinds(y int) []int {
+	kinds := make([]int, f.width)
+	for x := range kinds {
+		kinds[x] = f.Kind(x, y)
+	}
+
+	return kinds
+}
+
+// Set puts a block of colour c and kind in a cell. A nil colour empties it.
+func (f *Field) Set(x, y int, c color.Color, kind int) {
+	f.colors[y][x] = c
+	if c == nil {
+		f.rows[y] &^= 1 << x
+		f.kinds[y][x] = 0
+	} else {
+		f.rows[y] |= 1 << x
+		f.kinds[y][x] = uint8(kind + 1)
+	}
+}
+
+// ClearRow empties a row.
+func (f *Field) ClearRow(y int) {
+	f.rows[y] = 0
+	clear(f.colors[y])
+	clear(f.kinds[y])
+}
+
+func (f *Field) IsFull(y int) bool {
+	return f.rows[y] == f.full
+}
+
+func (f *Field) IsEmpty() bool {
+	for _, row := range f.rows {
+		if row != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// collides reports whether a piece at x, y overlaps a block or sticks out of
+// the field. Cells above the top of the field are free.
+func (f *Field) collides(tiles []Tile, x, y int) bool {
+	for _, tile := range tiles {
+		cx, cy := x+tile.x, y+tile.y
+
+		if cx < 0 || cx >= f.width || cy >= len(f.rows) {
+			return true
+		}
+
+		if cy >= 0 && f.rows[cy]&(1<<cx) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clearFullRows removes the full rows and moves the rows above them down.
+// The removed rows are emptied and reused at the top, so it doesn't
+// allocate. onClear is called with each full row before it is removed.
+func (f *Field) clearFullRows(onClear func(y int)) int {
+	cleared := 0
+	write := len(f.rows) - 1
+
+	for read := len(f.rows) - 1; read >= 0; read-- {
+		if f.rows[read] == f.full {
+			if onClear != nil {
+				onClear(read)
+			}
+			cleared++
+			continue
+		}
+
+		if read != write {
+			// Swap, so the colour row of a cleared line ends up above
+			f.rows[write], f.rows[read] = f.rows[read], f.rows[write]
+			f.colors[write], f.colors[read] = f.colors[read], f.colors[write]
+			f.kinds[write], f.kinds[read] = f.kinds[read], f.kinds[write]
+		}
+		write--
+	}
+
+	for y := write; y >= 0; y-- {
+		f.ClearRow(y)
+	}
+
+	return cleared
+}
+
+// pushUp moves every row of the field up by len(garbage) rows and puts the
+// garbage rows at the bottom. It returns true when a block is pushed off the
+// top of the field.
+func (f *Field) pushUp(garbage [][]color.Color) bool {
+	n := min(len(garbage), len(f.rows))
+	toppedOut := false
+
+	for y := 0; y < n; y++ {
+		if f.rows[y] != 0 {
+			toppedOut = true
+		}
+	}
+
+	// The rows pushed off the top are reused for the garbage
+	top := make([][]color.Color, n)
+	topKinds := make([][]uint8, n)
+	copy(top, f.colors[:n])
+	copy(topKinds, f.kinds[:n])
+	copy(f.rows, f.rows[n:])
+	copy(f.colors, f.colors[n:])
+	copy(f.kinds, f.kinds[n:])
+
+	for i := 0; i < n; i++ {
+		y := len(f.rows) - n + i
+		f.colors[y] = top[i]
+		f.kinds[y] = topKinds[i]
+		f.ClearRow(y)
+		for x, c := range garbage[len(garbage)-n+i] {
+			f.Set(x, y, c, garbageKind)
+		}
+	}
+
+	return toppedOut
+}
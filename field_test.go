@@ -0,0 +1,117 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestField_ClearFullRows(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+x.........
+xxxxxxxxxx
+.x........
+xxxxxxxxxx
+..x.......`))
+
+	var full []int
+	cleared := b.field.clearFullRows(func(y int) { full = append(full, y) })
+
+	if cleared != 2 || len(full) != 2 || full[0] != rows-2 || full[1] != rows-4 {
+		t.Fatalf("Expected rows %d and %d to be cleared, got %v", rows-2, rows-4, full)
+	}
+
+	expected := bottomRows(`
+x.........
+.x........
+..x.......`)
+	if got := fieldToString(&b.field); got != expected {
+		t.Errorf("Unexpected field after clearing.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	// The colours move with the rows and the reused rows are empty
+	for y := range b.field.Height() {
+		for x := range b.field.Width() {
+			if (b.field.Color(x, y) != nil) != b.field.Filled(x, y) {
+				t.Fatalf("Colour and mask disagree at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestField_KindsMoveWithRows(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xxxxxxxxx.`))
+
+	// A vertical I piece in the last column clears the bottom row
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceI], x: 9., y: 1.}
+	b.Fall()
+
+	for y := rows - 3; y < rows; y++ {
+		if got := b.field.Kind(9, y); got != int(PieceI) {
+			t.Errorf("Expected an I block at row %d, got kind %d", y, got)
+		}
+	}
+	if got := b.field.Kind(0, rows-1); got != -1 {
+		t.Errorf("Expected the cleared row to be empty, got kind %d", got)
+	}
+
+	garbage := [][]color.Color{make([]color.Color, cols)}
+	garbage[0][0] = colorGarbage
+	b.field.pushUp(garbage)
+
+	if got := b.field.Kind(9, rows-2); got != int(PieceI) {
+		t.Errorf("Expected the I block to rise with the garbage, got kind %d", got)
+	}
+	if got := b.field.Kind(0, rows-1); got != garbageKind {
+		t.Errorf("Expected a garbage block, got kind %d", got)
+	}
+	if got := b.field.Kind(1, rows-1); got != -1 {
+		t.Errorf("Expected the hole in the garbage to be empty, got kind %d", got)
+	}
+}
+
+func TestField_Collides(t *testing.T) {
+	f := createField(rows, cols)
+	f.Set(3, rows-1, color.White, garbageKind)
+	tiles := buildTiles()[PieceO].data[0]
+
+	tests := []struct {
+		x, y int
+		want bool
+	}{
+		{0, rows - 2, false},
+		{2, rows - 2, true}, // overlaps the block
+		{-1, 5, true},       // left wall
+		{cols - 1, 5, true}, // right wall
+		{0, rows - 1, true}, // floor
+		{0, -1, false},      // above the top is free
+	}
+
+	for _, tt := range tests {
+		if got := f.collides(tiles, tt.x, tt.y); got != tt.want {
+			t.Errorf("collides at %d,%d = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestLock_DoesNotAllocate(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+	p := &FallingPiece{}
+	i := 0
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		*p = b.dropPosition(FallingPiece{piece: b.tiles[PieceO], x: float64(i % 5 * 2), y: 1})
+		b.currentPiece = p
+		b.addCurrentPieceToTheBoard()
+		i++
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected no allocations per lock, got %.1f", allocs)
+	}
+	if b.totalNumberOfLinesCleared == 0 {
+		t.Errorf("Expected the locks to clear lines")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+// Every piece spawns in the first state of its buildTiles definition, with
+// its origin in column spawnX.
+const (
+	spawnX     = 4
+	spawnState = 0
+)
+
+// FinesseStats counts the placements that took more key presses than the
+// fastest way to reach the same column and orientation.
+type FinesseStats struct {
+	// Faults are measured against the fastest path using DAS, where holding
+	// a move to the wall is a single press.
+	Faults int
+	// TapFaults are measured against the fastest path tapping every move.
+	TapFaults int
+
+	Pieces [len(pieceNames)]PieceFinesse
+}
+
+// PieceFinesse is the finesse breakdown for one kind of piece.
+type PieceFinesse struct {
+	Placed int
+	Faults int
+	Wasted int // key presses over the fastest path using DAS
+}
+
+// record rates a placement that took presses key presses.
+func (s *FinesseStats) record(fp *FallingPiece, presses int) {
+	p := &s.Pieces[fp.piece.kind]
+	p.Placed++
+
+	if best := minimumKeyPressesDAS(fp); presses > best {
+		s.Faults++
+		p.Faults++
+		p.Wasted += presses - best
+	}
+
+	if presses > minimumKeyPresses(fp) {
+		s.TapFaults++
+	}
+}
+
+// finesseTable holds the fewest key presses needed to reach each orientation
+// and origin column of a piece, indexed [state][x]. Unreachable positions
+// are -1.
+type finesseTable [][]int
+
+// finesseTables are indexed by PieceKind, without and with DAS.
+var finesseTables, finesseTablesDAS = buildFinesseTables(buildTiles())
+
+func buildFinesseTables(pieces []Piece) (taps, das []finesseTable) {
+	taps = make([]finesseTable, len(pieces))
+	das = make([]finesseTable, len(pieces))
+	for _, p := range pieces {
+		taps[p.kind] = buildFinesseTable(p, false)
+		das[p.kind] = buildFinesseTable(p, true)
+	}
+
+	return taps, das
+}
+
+// buildFinesseTable searches the moves of a piece on an empty field,
+// breadth first from its spawn position. Rotations kick off the walls the
+// way Board.Rotate does. With DAS, moving to either wall is one press too.
+func buildFinesseTable(p Piece, das bool) finesseTable {
+	states := len(p.data)
+	minX := make([]int, states)
+	maxX := make([]int, states)
+	for s, tiles := range p.data {
+		minX[s], maxX[s] = cols, -cols
+		for _, tile := range tiles {
+			minX[s] = min(minX[s], tile.x)
+			maxX[s] = max(maxX[s], tile.x)
+		}
+	}
+
+	table := make(finesseTable, states)
+	for s := range table {
+		table[s] = make([]int, cols)
+		for x := range table[s] {
+			table[s][x] = -1
+		}
+	}
+
+	type position struct{ state, x int }
+	table[spawnState][spawnX] = 0
+	queue := []position{{spawnState, spawnX}}
+
+	for len(queue) > 0 {
+		pos := queue[0]
+		queue = queue[1:]
+		s := pos.state
+
+		next := []position{
+			{(s + 1) % states, pos.x},
+		}
+		if pos.x+minX[s] > 0 {
+			next = append(next, position{s, pos.x - 1})
+		}
+		if pos.x+maxX[s] < cols-1 {
+			next = append(next, position{s, pos.x + 1})
+		}
+		if das {
+			next = append(next, position{s, -minX[s]}, position{s, cols - 1 - maxX[s]})
+		}
+
+		for _, n := range next {
+			// wall kick
+			n.x = min(max(n.x, -minX[n.state]), cols-1-maxX[n.state])
+			if table[n.state][n.x] < 0 {
+				table[n.state][n.x] = table[s][pos.x] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return table
+}
+
+// minimumKeyPresses returns the fewest move and rotate key presses needed to
+// bring a piece from its spawn position to its current column and
+// orientation, tapping each move. Pieces only rotate clockwise.
+func minimumKeyPresses(fp *FallingPiece) int {
+	return finesseTables[fp.piece.kind][fp.state][int(fp.x)]
+}
+
+// minimumKeyPressesDAS is like minimumKeyPresses, but lets the piece be
+// moved to either wall with one long press.
+func minimumKeyPressesDAS(fp *FallingPiece) int {
+	return finesseTablesDAS[fp.piece.kind][fp.state][int(fp.x)]
+}
@@ -0,0 +1,132 @@
+package main
+
+import "image/color"
+
+// GarbageHoleRule decides where the hole of each garbage row goes.
+type GarbageHoleRule int
+
+const (
+	// GarbageHoleRandom puts the hole of every row in a random column.
+	GarbageHoleRandom GarbageHoleRule = iota
+	// GarbageHoleSameColumn keeps the hole in one column, so the rows can be
+	// cleared with a single I piece.
+	GarbageHoleSameColumn
+	// GarbageHoleMessy moves the hole to a random column with a chance of
+	// Messiness percent per row.
+	GarbageHoleMessy
+)
+
+type GarbageSettings struct {
+	Rule      GarbageHoleRule
+	Messiness int // percent, only used by GarbageHoleMessy
+}
+
+// QueueGarbage adds lines to the pending garbage meter. They rise up from the
+// bottom the next time a piece locks without clearing a line.
+func (b *Board) QueueGarbage(lines int) {
+	b.pendingGarbage += lines
+}
+
+// PendingGarbage returns the number of lines waiting in the garbage meter.
+func (b *Board) PendingGarbage() int {
+	return b.pendingGarbage
+}
+
+// cancelGarbage uses cleared lines to take garbage off the pending meter and
+// returns what is left of them.
+func (b *Board) cancelGarbage(lines int) int {
+	cancelled := min(lines, b.pendingGarbage)
+	b.pendingGarbage -= cancelled
+
+	return lines - cancelled
+}
+
+// TakeOutgoingGarbage returns the attack lines the board has sent since the
+// last call.
+func (b *Board) TakeOutgoingGarbage() int {
+	lines := b.outgoingGarbage
+	b.outgoingGarbage = 0
+
+	return lines
+}
+
+// AddGarbage immediately pushes lines of garbage up from the bottom of the
+// field. Blocks pushed off the top end the game.
+func (b *Board) AddGarbage(lines int) {
+	b.raiseGarbage(lines)
+
+	// Keep the falling piece on top of the new rows
+	if b.currentPiece != nil {
+		for b.currentPiece.y > 0 && b.checkCollision(b.currentPiece, 0, 0) {
+			b.currentPiece.y -= 1.0
+		}
+	}
+}
+
+// raisePendingGarbage pushes the whole garbage meter into the field. It is
+// called when a piece locks without clearing lines.
+func (b *Board) raisePendingGarbage() {
+	lines := b.pendingGarbage
+	b.pendingGarbage = 0
+	b.raiseGarbage(lines)
+}
+
+func (b *Board) raiseGarbage(lines int) {
+	if lines <= 0 {
+		return
+	}
+
+	garbage := make([][]color.Color, lines)
+	for i := range garbage {
+		garbage[i] = b.garbageRow()
+	}
+
+	if b.field.pushUp(garbage) {
+		b.gameOver = true
+		b.emit(BoardEvent{Kind: EventTopOut})
+	}
+}
+
+// GarbageRowsLeft counts the rows of the field that still contain garbage.
+func (b *Board) GarbageRowsLeft() int {
+	left := 0
+	for y := range b.field.Height() {
+		if b.field.isGarbageRow(y) {
+			left++
+		}
+	}
+
+	return left
+}
+
+func (f *Field) isGarbageRow(y int) bool {
+	for x := range f.width {
+		if f.Kind(x, y) == garbageKind {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *Board) garbageRow() []color.Color {
+	width := b.field.Width()
+
+	switch b.garbage.Rule {
+	case GarbageHoleRandom:
+		b.garbageHole = b.garbageRand.Intn(width)
+	case GarbageHoleMessy:
+		if b.garbageRand.Intn(100) < b.garbage.Messiness {
+			b.garbageHole = b.garbageRand.Intn(width)
+		}
+	}
+
+	row := make([]color.Color, width)
+	for x := range row {
+		if x != b.garbageHole {
+			row[x] = colorGarbage
+		}
+	}
+
+	return row
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestField_PushUpShiftsRows(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+x.........
+xx........
+xxx.......`))
+
+	garbage := [][]color.Color{
+		make([]color.Color, cols),
+		make([]color.Color, cols),
+	}
+	garbage[0][0] = colorGarbage
+	garbage[1][1] = colorGarbage
+
+	if b.field.pushUp(garbage) {
+		t.Fatalf("Expected no top out with an empty top of the field")
+	}
+
+	expected := bottomRows(`
+x.........
+xx........
+xxx.......
+x.........
+.x........`)
+	if got := fieldToString(&b.field); got != expected {
+		t.Errorf("Unexpected field after push up.\nExpected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	if b.field.Height() != rows {
+		t.Errorf("Expected the field to keep %d rows, got %d", rows, b.field.Height())
+	}
+}
+
+func TestField_PushUpDetectsTopOut(t *testing.T) {
+	b := NewBoard(rows, cols)
+	b.field.Set(5, 1, color.White, garbageKind)
+
+	oneRow := [][]color.Color{make([]color.Color, cols)}
+	if b.field.pushUp(oneRow) {
+		t.Fatalf("Expected no top out when the top row is empty")
+	}
+
+	if !b.field.Filled(5, 0) {
+		t.Fatalf("Expected the block to move to the top row")
+	}
+
+	if !b.field.pushUp(oneRow) {
+		t.Errorf("Expected a top out when a block is pushed off the top")
+	}
+}
+
+func TestField_PushUpMoreRowsThanField(t *testing.T) {
+	field := createField(3, cols)
+
+	garbage := make([][]color.Color, 5)
+	for i := range garbage {
+		garbage[i] = make([]color.Color, cols)
+		garbage[i][i] = colorGarbage
+	}
+
+	field.pushUp(garbage)
+
+	// Only the last three garbage rows fit
+	for y := 0; y < 3; y++ {
+		if !field.Filled(y+2, y) {
+			t.Errorf("Expected row %d to be garbage row %d", y, y+2)
+		}
+	}
+}
+
+func TestAddGarbage_HoleRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		settings   GarbageSettings
+		sameColumn bool
+	}{
+		{name: "random", settings: GarbageSettings{Rule: GarbageHoleRandom}},
+		{name: "same column", settings: GarbageSettings{Rule: GarbageHoleSameColumn}, sameColumn: true},
+		{name: "messy 0%", settings: GarbageSettings{Rule: GarbageHoleMessy, Messiness: 0}, sameColumn: true},
+		{name: "messy 100%", settings: GarbageSettings{Rule: GarbageHoleMessy, Messiness: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBoard(rows, cols)
+			b.garbage = tt.settings
+			b.AddGarbage(10)
+
+			holes := map[int]bool{}
+			for y := rows - 10; y < rows; y++ {
+				hole := -1
+				for x := 0; x < cols; x++ {
+					if !b.field.Filled(x, y) {
+						if hole != -1 {
+							t.Fatalf("Expected exactly one hole in row %d", y)
+						}
+						hole = x
+					}
+				}
+				if hole == -1 {
+					t.Fatalf("Expected a hole in row %d", y)
+				}
+				holes[hole] = true
+			}
+
+			if tt.sameColumn && len(holes) != 1 {
+				t.Errorf("Expected all holes in one column, got %d columns", len(holes))
+			}
+		})
+	}
+}
+
+func TestGarbage_PendingRisesOnLockWithoutClear(t *testing.T) {
+	b := NewBoard(rows, cols)
+	b.QueueGarbage(3)
+
+	b.currentPiece = &FallingPiece{piece: b.tiles[1], x: 0., y: 1.} // O piece
+	b.Fall()
+
+	if b.PendingGarbage() != 0 {
+		t.Errorf("Expected the meter to be empty, got %d", b.PendingGarbage())
+	}
+
+	// The O piece sits on top of the three garbage rows
+	if !b.field.Filled(0, rows-4) || !b.field.Filled(0, rows-5) {
+		t.Errorf("Expected the O piece to be pushed up by the garbage")
+	}
+	for y := rows - 3; y < rows; y++ {
+		if !b.field.isGarbageRow(y) {
+			t.Errorf("Expected row %d to be garbage", y)
+		}
+	}
+}
+
+func TestGarbage_ClearsCancelPending(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xxxxxxxxx.
+xxxxxxxxx.`))
+	b.QueueGarbage(3)
+
+	// Vertical I piece in the last column clears two lines
+	b.currentPiece = &FallingPiece{piece: b.tiles[0], x: 9., y: 1.}
+	b.Fall()
+
+	if b.totalNumberOfLinesCleared != 2 {
+		t.Fatalf("Expected 2 lines cleared, got %d", b.totalNumberOfLinesCleared)
+	}
+
+	if b.PendingGarbage() != 1 {
+		t.Errorf("Expected 1 line of garbage left after cancelling, got %d", b.PendingGarbage())
+	}
+
+	for y := 0; y < rows; y++ {
+		if b.field.isGarbageRow(y) {
+			t.Errorf("Expected no garbage to rise on a clearing lock, found it in row %d", y)
+		}
+	}
+}
+
+func TestGarbage_TopOut(t *testing.T) {
+	b := NewBoard(rows, cols)
+	b.field.Set(0, 2, color.White, garbageKind)
+
+	b.AddGarbage(2)
+	if b.gameOver {
+		t.Fatalf("Expected no game over while the blocks are still in the field")
+	}
+
+	b.AddGarbage(1)
+	if !b.gameOver {
+		t.Errorf("Expected a game over when garbage pushes blocks off the top")
+	}
+}
+
+// bottomRows pads a layout with empty rows on top so it fills the field.
+func bottomRows(layout string) string {
+	layout = strings.TrimSpace(layout)
+	lines := strings.Count(layout, "\n") + 1
+
+	return strings.Repeat("..........\n", rows-lines) + layout
+}
+
+// fieldToString is the inverse of fillBoardFromString.
+func fieldToString(field *Field) string {
+	s := ""
+	for y := range field.Height() {
+		if y > 0 {
+			s += "\n"
+		}
+		for x := range field.Width() {
+			if field.Filled(x, y) {
+				s += "x"
+			} else {
+				s += "."
+			}
+		}
+	}
+
+	return s
+}
+
+func TestDigMode(t *testing.T) {
+	b := NewBoard(rows, cols)
+	mode := DigMode{Rows: 10}
+	mode.Setup(b)
+
+	if b.GarbageRowsLeft() != 10 {
+		t.Fatalf("Expected 10 rows of cheese, got %d", b.GarbageRowsLeft())
+	}
+
+	if mode.Finished(b) {
+		t.Fatalf("Expected the race not to be finished with garbage left")
+	}
+
+	for y := range b.field.Height() {
+		b.field.ClearRow(y)
+	}
+
+	if !mode.Finished(b) {
+		t.Errorf("Expected the race to be finished once the garbage is gone")
+	}
+}
+
+func TestDigMode_EndlessRefills(t *testing.T) {
+	b := NewBoard(rows, cols)
+	mode := DigMode{Rows: 10, Endless: true}
+	mode.Setup(b)
+	b.countdown = 0
+
+	// Dig out the top four rows, then lock a piece
+	for y := rows - 10; y < rows-6; y++ {
+		b.field.ClearRow(y)
+	}
+	b.currentPiece = &FallingPiece{piece: b.tiles[1], x: 0., y: 1.} // O piece
+	b.Fall()
+
+	if b.GarbageRowsLeft() != 10 {
+		t.Errorf("Expected the garbage to be refilled to 10 rows, got %d", b.GarbageRowsLeft())
+	}
+
+	if mode.Finished(b) {
+		t.Errorf("Expected the endless dig never to finish")
+	}
+}
@@ -2,7 +2,10 @@ module github.com/renq/mletris
 
 go 1.24.8
 
-require github.com/hajimehoshi/ebiten/v2 v2.9.1
+require (
+	github.com/hajimehoshi/ebiten/v2 v2.9.1
+	golang.org/x/sys v0.36.0
+)
 
 require (
 	github.com/ebitengine/gomobile v0.0.0-20250923094054-ea854a63cce1 // indirect
@@ -13,6 +16,5 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/image v0.31.0 // indirect
 	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 )
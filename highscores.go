@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	maxHighScores   = 10
+	nameEntryLength = 3
+	nameEntryChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+	// marathonMode is the high score table key of the standard endless game.
+	marathonMode = "marathon"
+)
+
+type HighScore struct {
+	Name     string        `json:"name"`
+	Score    int           `json:"score"`
+	Lines    int           `json:"lines"`
+	Level    int           `json:"level"`
+	Duration time.Duration `json:"duration"`
+	Date     time.Time     `json:"date"`
+}
+
+// PersonalBest is the fastest finish of a mode ranked by time.
+type PersonalBest struct {
+	Ticks  int       `json:"ticks"`
+	Pieces int       `json:"pieces"`
+	Faults int       `json:"faults"`
+	Date   time.Time `json:"date"`
+}
+
+// HighScores keeps a top-10 table per game mode and the personal bests of
+// modes ranked by time. It is saved as JSON in the user config directory; an
+// empty path keeps the tables in memory only.
+type HighScores struct {
+	path   string
+	Tables map[string][]HighScore  `json:"tables"`
+	Bests  map[string]PersonalBest `json:"bests"`
+}
+
+// configFilePath returns the location of a file in the user config directory.
+func configFilePath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "mletris", name), nil
+}
+
+// LoadHighScores reads the tables from path. A missing file is not an error.
+func LoadHighScores(path string) (*HighScores, error) {
+	h := &HighScores{
+		path:   path,
+		Tables: map[string][]HighScore{},
+		Bests:  map[string]PersonalBest{},
+	}
+
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+
+	if err := json.Unmarshal(data, h); err != nil {
+		return h, err
+	}
+	if h.Tables == nil {
+		h.Tables = map[string][]HighScore{}
+	}
+	if h.Bests == nil {
+		h.Bests = map[string]PersonalBest{}
+	}
+
+	return h, nil
+}
+
+func (h *HighScores) Save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(h.path, data, 0o644)
+}
+
+func (h *HighScores) Table(mode string) []HighScore {
+	return h.Tables[mode]
+}
+
+// Qualifies reports whether a score would make it into the mode's table.
+func (h *HighScores) Qualifies(mode string, score int) bool {
+	if score <= 0 {
+		return false
+	}
+
+	table := h.Tables[mode]
+	if len(table) < maxHighScores {
+		return true
+	}
+
+	return score > table[len(table)-1].Score
+}
+
+// Add inserts the entry and returns its rank (0-based), or -1 when it didn't
+// make it into the table.
+func (h *HighScores) Add(mode string, entry HighScore) int {
+	table := h.Tables[mode]
+
+	// Older entries stay ahead of newer ones with the same score.
+	rank := sort.Search(len(table), func(i int) bool {
+		return table[i].Score < entry.Score
+	})
+	if rank >= maxHighScores {
+		return -1
+	}
+
+	table = slices.Insert(table, rank, entry)
+	if len(table) > maxHighScores {
+		table = table[:maxHighScores]
+	}
+	h.Tables[mode] = table
+
+	return rank
+}
+
+func (h *HighScores) Best(mode string) (PersonalBest, bool) {
+	pb, ok := h.Bests[mode]
+	return pb, ok
+}
+
+// RecordBest stores the result when it beats the mode's personal best and
+// reports whether it did.
+func (h *HighScores) RecordBest(mode string, pb PersonalBest) bool {
+	if best, ok := h.Bests[mode]; ok && best.Ticks <= pb.Ticks {
+		return false
+	}
+
+	h.Bests[mode] = pb
+	return true
+}
+
+// NameEntry is the arcade style three letter name input shown when a player
+// gets into the high score table.
+type NameEntry struct {
+	letters [nameEntryLength]int
+	cursor  int
+}
+
+func NewNameEntry() *NameEntry {
+	return &NameEntry{}
+}
+
+func (e *NameEntry) ChangeLetter(delta int) {
+	n := len(nameEntryChars)
+	e.letters[e.cursor] = ((e.letters[e.cursor]+delta)%n + n) % n
+}
+
+func (e *NameEntry) MoveCursor(delta int) {
+	e.cursor = min(max(e.cursor+delta, 0), nameEntryLength-1)
+}
+
+func (e *NameEntry) Name() string {
+	name := make([]byte, nameEntryLength)
+	for i, letter := range e.letters {
+		name[i] = nameEntryChars[letter]
+	}
+
+	return string(name)
+}
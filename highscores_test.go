@@ -0,0 +1,82 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHighScores_AddKeepsTopTen(t *testing.T) {
+	h, _ := LoadHighScores("")
+
+	for score := 1; score <= 15; score++ {
+		h.Add(marathonMode, HighScore{Name: "AAA", Score: score * 100})
+	}
+
+	table := h.Table(marathonMode)
+	if len(table) != maxHighScores {
+		t.Fatalf("Expected %d entries, got %d", maxHighScores, len(table))
+	}
+
+	if table[0].Score != 1500 || table[maxHighScores-1].Score != 600 {
+		t.Errorf("Expected scores from 1500 down to 600, got %d..%d", table[0].Score, table[maxHighScores-1].Score)
+	}
+
+	if h.Qualifies(marathonMode, 600) {
+		t.Errorf("Expected a score equal to the lowest entry not to qualify")
+	}
+
+	if !h.Qualifies(marathonMode, 601) {
+		t.Errorf("Expected a score above the lowest entry to qualify")
+	}
+}
+
+func TestHighScores_AddRanksEqualEntries(t *testing.T) {
+	h, _ := LoadHighScores("")
+	entry := HighScore{Name: "AAA", Score: 500, Date: time.Now()}
+
+	for want := range 3 {
+		if rank := h.Add(marathonMode, entry); rank != want {
+			t.Errorf("Expected the same entry added again to rank %d, got %d", want, rank)
+		}
+	}
+
+	if rank := h.Add(marathonMode, HighScore{Name: "BOB", Score: 600}); rank != 0 {
+		t.Errorf("Expected a higher score to rank 0, got %d", rank)
+	}
+
+	for range maxHighScores {
+		h.Add(marathonMode, entry)
+	}
+	if rank := h.Add(marathonMode, entry); rank != -1 {
+		t.Errorf("Expected an entry below a full table not to rank, got %d", rank)
+	}
+}
+
+func TestHighScores_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mletris", "highscores.json")
+
+	h, err := LoadHighScores(path)
+	if err != nil {
+		t.Fatalf("Expected a missing file to be ignored, got %v", err)
+	}
+
+	rank := h.Add(marathonMode, HighScore{Name: "BOB", Score: 1200, Lines: 4})
+	if rank != 0 {
+		t.Errorf("Expected rank 0, got %d", rank)
+	}
+
+	if err := h.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadHighScores(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	table := loaded.Table(marathonMode)
+	if len(table) != 1 || table[0].Name != "BOB" || table[0].Score != 1200 {
+		t.Errorf("Expected the saved entry back, got %+v", table)
+	}
+}
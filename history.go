@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	historyFile = "history.csv"
+	sessionsDir = "sessions"
+)
+
+var historyHeader = []string{
+	"date", "mode", "seed", "finished", "score", "lines", "level", "ticks", "pieces",
+	"singles", "doubles", "triples", "tetrises", "key_presses", "finesse_faults",
+}
+
+// Session is the record of a game, written when it ends. The history log
+// keeps one CSV row per session; the level splits and settings are only in
+// the session's own JSON file.
+type Session struct {
+	Date     time.Time `json:"date"`
+	Mode     string    `json:"mode"`
+	Seed     int64     `json:"seed"`
+	Settings Settings  `json:"settings"`
+	Finished bool      `json:"finished"` // the mode's goal was reached
+
+	Score  int          `json:"score"`
+	Lines  int          `json:"lines"`
+	Level  int          `json:"level"`
+	Ticks  int          `json:"ticks"`
+	Pieces int          `json:"pieces"`
+	Clears LineClears   `json:"clears"`
+	Splits []LevelSplit `json:"splits"`
+
+	KeyPresses    int `json:"key_presses"`
+	FinesseFaults int `json:"finesse_faults"`
+}
+
+type LineClears struct {
+	Singles  int `json:"singles"`
+	Doubles  int `json:"doubles"`
+	Triples  int `json:"triples"`
+	Tetrises int `json:"tetrises"`
+}
+
+// LevelSplit is the time spent in a level.
+type LevelSplit struct {
+	Level int `json:"level"`
+	Ticks int `json:"ticks"`
+}
+
+func newSession(mode Mode, b *Board, settings *Settings, date time.Time) Session {
+	s := Session{
+		Date:     date,
+		Mode:     mode.ID(),
+		Seed:     b.seed,
+		Settings: *settings,
+		Finished: b.finished,
+		Score:    b.Score,
+		Lines:    b.totalNumberOfLinesCleared,
+		Level:    b.Level,
+		Ticks:    b.ticksPlayed,
+		Pieces:   b.piecesPlaced,
+		Clears: LineClears{
+			Singles:  b.stats.Clears[1],
+			Doubles:  b.stats.Clears[2],
+			Triples:  b.stats.Clears[3],
+			Tetrises: b.stats.Clears[4],
+		},
+		KeyPresses:    b.stats.KeyPresses,
+		FinesseFaults: b.finesse.Faults,
+	}
+
+	start := 0
+	for i, ticks := range slices.Concat(b.stats.LevelTicks, []int{b.ticksPlayed}) {
+		s.Splits = append(s.Splits, LevelSplit{Level: b.startLevel + i, Ticks: ticks - start})
+		start = ticks
+	}
+
+	return s
+}
+
+// saveSession writes the session to its own JSON file in dir and appends it
+// to the history log.
+func saveSession(dir string, s Session) error {
+	if err := os.MkdirAll(filepath.Join(dir, sessionsDir), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeSessionFile(filepath.Join(dir, sessionsDir), s, data); err != nil {
+		return err
+	}
+
+	return appendHistory(filepath.Join(dir, historyFile), s)
+}
+
+// writeSessionFile writes a session to a new file named after its date and
+// mode. Sessions ending in the same second get a number added to the name
+// instead of overwriting each other.
+func writeSessionFile(dir string, s Session, data []byte) error {
+	base := fmt.Sprintf("%s-%s", s.Date.Format("20060102-150405"), s.Mode)
+	name := base + ".json"
+	for n := 2; ; n++ {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			name = fmt.Sprintf("%s-%d.json", base, n)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+func appendHistory(path string, s Session) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		w.Write(historyHeader)
+	}
+
+	itoa := strconv.Itoa
+	w.Write([]string{
+		s.Date.Format(time.RFC3339), s.Mode, strconv.FormatInt(s.Seed, 10), strconv.FormatBool(s.Finished),
+		itoa(s.Score), itoa(s.Lines), itoa(s.Level), itoa(s.Ticks), itoa(s.Pieces),
+		itoa(s.Clears.Singles), itoa(s.Clears.Doubles), itoa(s.Clears.Triples), itoa(s.Clears.Tetrises),
+		itoa(s.KeyPresses), itoa(s.FinesseFaults),
+	})
+	w.Flush()
+
+	return w.Error()
+}
+
+// LoadHistory reads the sessions in the history log, oldest first. A missing
+// log is an empty history.
+func LoadHistory(path string) ([]Session, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(historyHeader)
+	if _, err := r.Read(); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []Session
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return sessions, nil
+		}
+		if err != nil {
+			return sessions, err
+		}
+
+		s, err := parseHistoryRow(row)
+		if err != nil {
+			return sessions, fmt.Errorf("%s: %w", path, err)
+		}
+		sessions = append(sessions, s)
+	}
+}
+
+func parseHistoryRow(row []string) (Session, error) {
+	var s Session
+	var err error
+	if s.Date, err = time.Parse(time.RFC3339, row[0]); err != nil {
+		return s, err
+	}
+	s.Mode = row[1]
+	if s.Seed, err = strconv.ParseInt(row[2], 10, 64); err != nil {
+		return s, err
+	}
+	if s.Finished, err = strconv.ParseBool(row[3]); err != nil {
+		return s, err
+	}
+
+	ints := []*int{
+		&s.Score, &s.Lines, &s.Level, &s.Ticks, &s.Pieces,
+		&s.Clears.Singles, &s.Clears.Doubles, &s.Clears.Triples, &s.Clears.Tetrises,
+		&s.KeyPresses, &s.FinesseFaults,
+	}
+	for i, v := range ints {
+		if *v, err = strconv.Atoi(row[4+i]); err != nil {
+			return s, err
+		}
+	}
+
+	return s, nil
+}
+
+// HistorySummary sums up the sessions of one mode.
+type HistorySummary struct {
+	Mode      string
+	Games     int
+	Ticks     int
+	Pieces    int
+	Lines     int
+	BestScore int
+	BestTicks int // fastest finish, 0 when the mode was never finished
+}
+
+// summariseHistory groups the sessions by mode, in the order the modes were
+// first played.
+func summariseHistory(sessions []Session) []HistorySummary {
+	var summaries []HistorySummary
+	index := map[string]int{}
+	for _, s := range sessions {
+		i, ok := index[s.Mode]
+		if !ok {
+			i = len(summaries)
+			index[s.Mode] = i
+			summaries = append(summaries, HistorySummary{Mode: s.Mode})
+		}
+
+		sum := &summaries[i]
+		sum.Games++
+		sum.Ticks += s.Ticks
+		sum.Pieces += s.Pieces
+		sum.Lines += s.Lines
+		sum.BestScore = max(sum.BestScore, s.Score)
+		if s.Finished && (sum.BestTicks == 0 || s.Ticks < sum.BestTicks) {
+			sum.BestTicks = s.Ticks
+		}
+	}
+
+	return summaries
+}
+
+// runHistoryCommand lists the last sessions of the history log and sums
+// them up per mode.
+func runHistoryCommand(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	mode := flags.String("mode", "", "only show modes whose ID starts with this")
+	last := flags.Int("n", 20, "number of sessions to list")
+	dir := flags.String("dir", "", "directory of the history log (default: history in the config directory)")
+	flags.Parse(args)
+
+	if *dir == "" {
+		var err error
+		if *dir, err = configFilePath("history"); err != nil {
+			return err
+		}
+	}
+
+	sessions, err := LoadHistory(filepath.Join(*dir, historyFile))
+	if err != nil {
+		return err
+	}
+
+	filtered := sessions[:0]
+	for _, s := range sessions {
+		if strings.HasPrefix(s.Mode, *mode) {
+			filtered = append(filtered, s)
+		}
+	}
+	sessions = filtered
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(sessions) == 0 {
+		fmt.Fprintln(w, "no sessions played yet")
+		return w.Flush()
+	}
+
+	fmt.Fprintln(w, "DATE\tMODE\tSCORE\tLINES\tTIME\tPPS\tKPP\tFINESSE")
+	for _, s := range sessions[max(len(sessions)-*last, 0):] {
+		pps, kpp := 0., 0.
+		if s.Ticks > 0 {
+			pps = float64(s.Pieces) * ticksPerSecond / float64(s.Ticks)
+		}
+		if s.Pieces > 0 {
+			kpp = float64(s.KeyPresses) / float64(s.Pieces)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%.2f\t%.2f\t%d\n",
+			s.Date.Local().Format("2006-01-02 15:04"), s.Mode, s.Score, s.Lines, formatTicks(s.Ticks), pps, kpp, s.FinesseFaults)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "MODE\tGAMES\tPLAYED\tLINES\tBEST SCORE\tBEST TIME")
+	for _, sum := range summariseHistory(sessions) {
+		best := "-"
+		if sum.BestTicks > 0 {
+			best = formatTicks(sum.BestTicks)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\n", sum.Mode, sum.Games, formatTicks(sum.Ticks), sum.Lines, sum.BestScore, best)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewSession_LevelSplits(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 42)
+	b.SetStartLevel(2)
+	b.ticksPlayed = 500
+	b.stats.LevelTicks = []int{100, 300}
+	b.Level = 4
+
+	s := newSession(MarathonMode{}, b, DefaultSettings(), time.Now())
+
+	expected := []LevelSplit{{Level: 2, Ticks: 100}, {Level: 3, Ticks: 200}, {Level: 4, Ticks: 200}}
+	if len(s.Splits) != len(expected) {
+		t.Fatalf("Expected %d splits, got %+v", len(expected), s.Splits)
+	}
+	for i := range expected {
+		if s.Splits[i] != expected[i] {
+			t.Errorf("Expected split %d to be %+v, got %+v", i, expected[i], s.Splits[i])
+		}
+	}
+
+	if s.Seed != 42 || s.Mode != marathonMode {
+		t.Errorf("Expected seed 42 in %s, got %d in %s", marathonMode, s.Seed, s.Mode)
+	}
+}
+
+func TestSaveSession_AppendsHistory(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	sessions := []Session{
+		{Date: date, Mode: "sprint-40", Seed: 1, Finished: true, Score: 100, Lines: 40, Ticks: 3000, Pieces: 100, KeyPresses: 250, Clears: LineClears{Tetrises: 10}},
+		{Date: date.Add(time.Hour), Mode: "sprint-40", Seed: 2, Finished: true, Lines: 40, Ticks: 2500},
+		{Date: date.Add(2 * time.Hour), Mode: marathonMode, Seed: 3, Score: 5000, Lines: 20, Ticks: 4000},
+	}
+	for _, s := range sessions {
+		if err := saveSession(dir, s); err != nil {
+			t.Fatalf("Could not save the session: %v", err)
+		}
+	}
+
+	loaded, err := LoadHistory(filepath.Join(dir, historyFile))
+	if err != nil {
+		t.Fatalf("Could not load the history: %v", err)
+	}
+	if len(loaded) != len(sessions) {
+		t.Fatalf("Expected %d sessions, got %d", len(sessions), len(loaded))
+	}
+	if got := loaded[0]; !got.Date.Equal(date) || got.Clears.Tetrises != 10 || got.KeyPresses != 250 || !got.Finished {
+		t.Errorf("Unexpected first session %+v", got)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, sessionsDir, "20260501-120000-sprint-40.json"))
+	if err != nil {
+		t.Fatalf("Expected a session file: %v", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil || s.Score != 100 {
+		t.Errorf("Unexpected session file %s (%v)", data, err)
+	}
+
+	summaries := summariseHistory(loaded)
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 modes, got %+v", summaries)
+	}
+	if sprint := summaries[0]; sprint.Games != 2 || sprint.BestTicks != 2500 || sprint.BestScore != 100 {
+		t.Errorf("Unexpected sprint summary %+v", sprint)
+	}
+	if marathon := summaries[1]; marathon.BestTicks != 0 || marathon.Lines != 20 {
+		t.Errorf("Unexpected marathon summary %+v", marathon)
+	}
+}
+
+func TestSaveSession_SameSecond(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for seed := range int64(3) {
+		if err := saveSession(dir, Session{Date: date, Mode: "sprint-40", Seed: seed}); err != nil {
+			t.Fatalf("Could not save the session: %v", err)
+		}
+	}
+
+	for _, name := range []string{"20260501-120000-sprint-40.json", "20260501-120000-sprint-40-2.json", "20260501-120000-sprint-40-3.json"} {
+		if _, err := os.Stat(filepath.Join(dir, sessionsDir, name)); err != nil {
+			t.Errorf("Expected a session file: %v", err)
+		}
+	}
+}
+
+func TestLoadHistory_Missing(t *testing.T) {
+	sessions, err := LoadHistory(filepath.Join(t.TempDir(), historyFile))
+	if err != nil || sessions != nil {
+		t.Errorf("Expected an empty history, got %v (%v)", sessions, err)
+	}
+}
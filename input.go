@@ -5,47 +5,124 @@ import (
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
-const (
-	pressDelayTicks          = 10 // 1/6 of second
-	pressRepeatIntervalTicks = 2 // 1/30 of second
-)
-
-type InputHandler struct{}
+// Controls map keys and a gamepad to the game buttons of one player.
+type Controls struct {
+	Keys    map[Buttons][]ebiten.Key
+	Gamepad int // index of the player's gamepad among the connected ones, -1 for none
+}
 
-func (i *InputHandler) Update(board *Board) {
-	if board == nil {
-		return
+var (
+	// defaultControls are used in single player games.
+	defaultControls = Controls{
+		Keys: map[Buttons][]ebiten.Key{
+			ButtonLeft:     {ebiten.KeyArrowLeft, ebiten.KeyA},
+			ButtonRight:    {ebiten.KeyArrowRight, ebiten.KeyD},
+			ButtonSoftDrop: {ebiten.KeyArrowDown, ebiten.KeyS},
+			ButtonRotate:   {ebiten.KeyArrowUp, ebiten.KeyW},
+			ButtonHardDrop: {ebiten.KeySpace},
+		},
+		Gamepad: 0,
 	}
 
-	// Handle pause/unpause toggle first.
-	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
-		board.TogglePause()
+	// versusControls split the keyboard in two halves for local versus.
+	versusControls = []Controls{
+		{
+			Keys: map[Buttons][]ebiten.Key{
+				ButtonLeft:     {ebiten.KeyA},
+				ButtonRight:    {ebiten.KeyD},
+				ButtonSoftDrop: {ebiten.KeyS},
+				ButtonRotate:   {ebiten.KeyW},
+				ButtonHardDrop: {ebiten.KeySpace},
+			},
+			Gamepad: 0,
+		},
+		{
+			Keys: map[Buttons][]ebiten.Key{
+				ButtonLeft:     {ebiten.KeyArrowLeft},
+				ButtonRight:    {ebiten.KeyArrowRight},
+				ButtonSoftDrop: {ebiten.KeyArrowDown},
+				ButtonRotate:   {ebiten.KeyArrowUp},
+				ButtonHardDrop: {ebiten.KeyEnter, ebiten.KeyShiftRight},
+			},
+			Gamepad: 1,
+		},
 	}
 
-	// If the game is stopped (paused or game over), don't process any other input.
-	if board.isStopped() {
-		return
+	gamepadButtons = map[Buttons][]ebiten.StandardGamepadButton{
+		ButtonLeft:     {ebiten.StandardGamepadButtonLeftLeft},
+		ButtonRight:    {ebiten.StandardGamepadButtonLeftRight},
+		ButtonSoftDrop: {ebiten.StandardGamepadButtonLeftBottom},
+		ButtonRotate:   {ebiten.StandardGamepadButtonRightBottom, ebiten.StandardGamepadButtonRightRight},
+		ButtonHardDrop: {ebiten.StandardGamepadButtonLeftTop},
 	}
+)
+
+type InputHandler struct {
+	settings   *Settings
+	controls   Controls
+	controller *Controller
+}
+
+func NewInputHandler(settings *Settings) *InputHandler {
+	return NewPlayerInputHandler(settings, defaultControls)
+}
 
-	if keyPressAndMove(ebiten.KeyArrowLeft) || keyPressAndMove(ebiten.KeyA) {
-		board.MoveLeft()
+// NewPlayerInputHandler creates an input handler for one player of a game
+// with several boards.
+func NewPlayerInputHandler(settings *Settings, controls Controls) *InputHandler {
+	return &InputHandler{
+		settings:   settings,
+		controls:   controls,
+		controller: NewController(settings),
 	}
+}
 
-	if keyPressAndMove(ebiten.KeyArrowRight) || keyPressAndMove(ebiten.KeyD) {
-		board.MoveRight()
+// Update should be called every tick while a board is shown, even when it is
+// paused, so that buttons held through a menu don't count as new presses.
+func (i *InputHandler) Update(board *Board) {
+	i.controller.Apply(board, i.Buttons())
+}
+
+func (i *InputHandler) Reset() {
+	i.controller.Reset()
+}
+
+// Buttons returns the game buttons the player is holding down.
+func (i *InputHandler) Buttons() Buttons {
+	var buttons Buttons
+
+	for button, keys := range i.controls.Keys {
+		for _, key := range keys {
+			if ebiten.IsKeyPressed(key) {
+				buttons |= button
+			}
+		}
 	}
 
-	if keyPressAndMove(ebiten.KeyArrowDown) || keyPressAndMove(ebiten.KeyS) {
-		board.MoveDown()
+	if id, ok := i.gamepad(); ok {
+		for button, padButtons := range gamepadButtons {
+			for _, padButton := range padButtons {
+				if ebiten.IsStandardGamepadButtonPressed(id, padButton) {
+					buttons |= button
+				}
+			}
+		}
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		board.Fall()
+	return buttons
+}
+
+func (i *InputHandler) gamepad() (ebiten.GamepadID, bool) {
+	if i.controls.Gamepad < 0 {
+		return 0, false
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
-		board.Rotate()
+	ids := ebiten.AppendGamepadIDs(nil)
+	if i.controls.Gamepad >= len(ids) || !ebiten.IsStandardGamepadLayoutAvailable(ids[i.controls.Gamepad]) {
+		return 0, false
 	}
+
+	return ids[i.controls.Gamepad], true
 }
 
 func keyPressAndMove(key ebiten.Key) bool {
@@ -53,3 +130,107 @@ func keyPressAndMove(key ebiten.Key) bool {
 		(inpututil.KeyPressDuration(key) > pressDelayTicks &&
 			inpututil.KeyPressDuration(key)%pressRepeatIntervalTicks == 0)
 }
+
+func buttonPressAndMove(id ebiten.GamepadID, button ebiten.StandardGamepadButton) bool {
+	duration := inpututil.StandardGamepadButtonPressDuration(id, button)
+	return inpututil.IsStandardGamepadButtonJustPressed(id, button) ||
+		(duration > pressDelayTicks && duration%pressRepeatIntervalTicks == 0)
+}
+
+// PausePressed reports whether the player asked to pause or unpause the game.
+func (i *InputHandler) PausePressed() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return true
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FullscreenPressed reports whether the player asked to switch between the
+// window and fullscreen.
+func (i *InputHandler) FullscreenPressed() bool {
+	return inpututil.IsKeyJustPressed(ebiten.KeyF11)
+}
+
+// StatsPressed reports whether the player asked to switch between the score
+// and the statistics page.
+func (i *InputHandler) StatsPressed() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		return true
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterLeft) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MenuAction translates keyboard and gamepad input into menu navigation.
+func (i *InputHandler) MenuAction() MenuAction {
+	switch {
+	case keyPressAndMove(ebiten.KeyArrowUp) || keyPressAndMove(ebiten.KeyW):
+		return menuUp
+	case keyPressAndMove(ebiten.KeyArrowDown) || keyPressAndMove(ebiten.KeyS):
+		return menuDown
+	case keyPressAndMove(ebiten.KeyArrowLeft) || keyPressAndMove(ebiten.KeyA):
+		return menuLeft
+	case keyPressAndMove(ebiten.KeyArrowRight) || keyPressAndMove(ebiten.KeyD):
+		return menuRight
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace):
+		return menuConfirm
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape) || inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		return menuBack
+	}
+
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+
+		switch {
+		case buttonPressAndMove(id, ebiten.StandardGamepadButtonLeftTop):
+			return menuUp
+		case buttonPressAndMove(id, ebiten.StandardGamepadButtonLeftBottom):
+			return menuDown
+		case buttonPressAndMove(id, ebiten.StandardGamepadButtonLeftLeft):
+			return menuLeft
+		case buttonPressAndMove(id, ebiten.StandardGamepadButtonLeftRight):
+			return menuRight
+		case inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightBottom),
+			inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight):
+			return menuConfirm
+		case inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightRight):
+			return menuBack
+		}
+	}
+
+	return menuNone
+}
+
+// UpdateNameEntry handles the high score name input. It returns true once the
+// name has been confirmed.
+func (i *InputHandler) UpdateNameEntry(e *NameEntry) bool {
+	switch i.MenuAction() {
+	case menuUp:
+		e.ChangeLetter(1)
+	case menuDown:
+		e.ChangeLetter(-1)
+	case menuLeft:
+		e.MoveCursor(-1)
+	case menuRight:
+		e.MoveCursor(1)
+	case menuConfirm:
+		return true
+	}
+
+	return false
+}
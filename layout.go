@@ -0,0 +1,131 @@
+package main
+
+import (
+	"image"
+	"math"
+)
+
+// textPageLines is how many lines fit on a text page.
+const textPageLines = 10
+
+const (
+	panelMargin  = 10 // space between the panels and the edge of their area
+	panelPadding = 20 // space between panels side by side
+	scoreWidth   = 80 // room for the score and HUD left of the board
+	columnGap    = 6  // space between the board and the column right of it
+	columnWidth  = 50 // room for the score and HUD in a column
+)
+
+// screenSize returns the size of the logical screen for a window of
+// outsideW by outsideH pixels. The screen is at least screenW by screenH
+// and has the shape of the window, so wide and tall windows give the
+// panels more room. A pixel perfect screen is scaled up by a whole number,
+// so every logical pixel is a square of window pixels.
+func screenSize(outsideW, outsideH int, pixelPerfect bool) (int, int) {
+	scale := min(float64(outsideW)/screenW, float64(outsideH)/screenH)
+	if scale <= 0 {
+		return screenW, screenH
+	}
+
+	if pixelPerfect && scale >= 1 {
+		s := int(scale)
+		return outsideW / s, outsideH / s
+	}
+
+	return int(math.Round(float64(outsideW) / scale)), int(math.Round(float64(outsideH) / scale))
+}
+
+// Panels are the positions of a board and the panels around it, and the
+// size of its tiles.
+type Panels struct {
+	TileSize int
+	Board    image.Point
+	Next     image.Point
+	Score    image.Point
+}
+
+// layoutPanels fits a board with its next piece and score into an area,
+// with tiles as big as fit. On wide areas the score is left of the board
+// and the next piece right of it. When stacking the next piece and the
+// score in a column right of the board gives bigger tiles, e.g. on tall
+// areas and split screens, the column is used instead.
+func layoutPanels(area image.Rectangle, rows, cols int) Panels {
+	height := (area.Dy() - 2*panelMargin) / rows
+
+	wide := (area.Dx() - 2*panelMargin - scoreWidth - 2*panelPadding) / (cols + 4)
+	wide = max(1, min(height, wide))
+
+	room := area.Dx() - 2*panelMargin - columnGap
+	column := max(1, min(height, room/(cols+4), (room-columnWidth)/cols))
+
+	var p Panels
+	if wide >= column {
+		t := wide
+		width := scoreWidth + panelPadding + cols*t + panelPadding + 4*t
+		x := area.Min.X + (area.Dx()-width)/2
+		y := area.Min.Y + (area.Dy()-rows*t)/2
+
+		p.TileSize = t
+		p.Score = image.Pt(x, y)
+		p.Board = image.Pt(x+scoreWidth+panelPadding, y)
+		p.Next = image.Pt(p.Board.X+cols*t+panelPadding, y)
+		return p
+	}
+
+	t := column
+	width := cols*t + columnGap + max(4*t, columnWidth)
+	x := area.Min.X + (area.Dx()-width)/2
+	y := area.Min.Y + (area.Dy()-rows*t)/2
+
+	p.TileSize = t
+	p.Board = image.Pt(x, y)
+	p.Next = image.Pt(x+cols*t+columnGap, y)
+	p.Score = image.Pt(p.Next.X, y+4*t+8)
+	return p
+}
+
+// splitArea returns the i-th of n equal parts of an area, side by side on
+// wide areas and one above the other on tall ones.
+func splitArea(area image.Rectangle, n, i int) image.Rectangle {
+	if area.Dx() >= area.Dy() {
+		w := area.Dx() / n
+		return image.Rect(area.Min.X+i*w, area.Min.Y, area.Min.X+(i+1)*w, area.Max.Y)
+	}
+
+	h := area.Dy() / n
+	return image.Rect(area.Min.X, area.Min.Y+i*h, area.Max.X, area.Min.Y+(i+1)*h)
+}
+
+// textPages splits lines into pages of at most perPage lines. A page ends
+// at its last blank line when it has one, so paragraphs stay together, and
+// blank lines at the top of a page are dropped.
+func textPages(lines []string, perPage int) [][]string {
+	var pages [][]string
+	for {
+		for len(lines) > 0 && lines[0] == "" {
+			lines = lines[1:]
+		}
+		if len(lines) <= perPage {
+			if len(lines) > 0 || pages == nil {
+				pages = append(pages, lines)
+			}
+			return pages
+		}
+
+		end := perPage
+		for i := perPage; i > 0; i-- {
+			if lines[i] == "" {
+				end = i
+				break
+			}
+		}
+		pages = append(pages, lines[:end])
+		lines = lines[end:]
+	}
+}
+
+// pageOrigin returns where to draw a page designed for a screenW by
+// screenH screen, such as a menu, so it is centred in a bigger area.
+func pageOrigin(area image.Rectangle) image.Point {
+	return image.Pt(area.Min.X+max(0, area.Dx()-screenW)/2, area.Min.Y+max(0, area.Dy()-screenH)/2)
+}
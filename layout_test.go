@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image"
+	"slices"
+	"testing"
+)
+
+func TestScreenSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		outsideW     int
+		outsideH     int
+		pixelPerfect bool
+		wantW, wantH int
+	}{
+		{name: "4:3 window", outsideW: 1024, outsideH: 768, wantW: 320, wantH: 240},
+		{name: "4:3 pixel perfect", outsideW: 1024, outsideH: 768, pixelPerfect: true, wantW: 341, wantH: 256},
+		{name: "exact multiple", outsideW: 960, outsideH: 720, pixelPerfect: true, wantW: 320, wantH: 240},
+		{name: "wide window", outsideW: 1920, outsideH: 1080, wantW: 427, wantH: 240},
+		{name: "portrait phone", outsideW: 1080, outsideH: 1920, pixelPerfect: true, wantW: 360, wantH: 640},
+		{name: "small window", outsideW: 160, outsideH: 120, pixelPerfect: true, wantW: 320, wantH: 240},
+		{name: "minimised", outsideW: 0, outsideH: 0, wantW: screenW, wantH: screenH},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h := screenSize(tt.outsideW, tt.outsideH, tt.pixelPerfect)
+			if w != tt.wantW || h != tt.wantH {
+				t.Errorf("Expected a %dx%d screen, got %dx%d", tt.wantW, tt.wantH, w, h)
+			}
+		})
+	}
+}
+
+func TestLayoutPanels(t *testing.T) {
+	// The default screen keeps the score, board and next piece side by side
+	p := layoutPanels(image.Rect(0, 0, screenW, screenH), rows, cols)
+	want := Panels{TileSize: 9, Score: image.Pt(37, 12), Board: image.Pt(137, 12), Next: image.Pt(247, 12)}
+	if p != want {
+		t.Errorf("Expected %+v on the default screen, got %+v", want, p)
+	}
+
+	// A portrait screen stacks the next piece and score right of the board
+	p = layoutPanels(image.Rect(0, 0, 360, 640), rows, cols)
+	if p.TileSize != 23 || p.Next.X != p.Board.X+cols*23+columnGap || p.Score.X != p.Next.X || p.Score.Y <= p.Next.Y {
+		t.Errorf("Expected a column layout with big tiles, got %+v", p)
+	}
+	if right := p.Next.X + 4*p.TileSize; right > 360 {
+		t.Errorf("Expected the panels to fit the screen, they end at %d", right)
+	}
+
+	// Each half of a split screen gets a column layout in its own half
+	half := splitArea(image.Rect(0, 0, screenW, screenH), 2, 1)
+	p = layoutPanels(half, rows, cols)
+	if p.TileSize != 8 || p.Board.X < half.Min.X || p.Next.X+columnWidth > half.Max.X {
+		t.Errorf("Expected 8 pixel tiles within %v, got %+v", half, p)
+	}
+}
+
+func TestSplitArea(t *testing.T) {
+	wide := image.Rect(0, 0, 320, 240)
+	if got := splitArea(wide, 2, 1); got != image.Rect(160, 0, 320, 240) {
+		t.Errorf("Expected a wide area to be split into columns, got %v", got)
+	}
+
+	tall := image.Rect(0, 0, 360, 640)
+	if got := splitArea(tall, 2, 1); got != image.Rect(0, 320, 360, 640) {
+		t.Errorf("Expected a tall area to be split into rows, got %v", got)
+	}
+}
+
+func TestPageOrigin(t *testing.T) {
+	if got := pageOrigin(image.Rect(0, 0, screenW, screenH)); got != image.Pt(0, 0) {
+		t.Errorf("Expected pages at the top left of the default screen, got %v", got)
+	}
+
+	if got := pageOrigin(image.Rect(0, 0, 360, 640)); got != image.Pt(20, 200) {
+		t.Errorf("Expected pages to be centred, got %v", got)
+	}
+}
+
+func TestTextPages(t *testing.T) {
+	lines := []string{"a", "b", "", "c", "d", "", "e"}
+
+	tests := []struct {
+		name    string
+		perPage int
+		want    [][]string
+	}{
+		{name: "fits one page", perPage: 10, want: [][]string{lines}},
+		{name: "breaks at blank lines", perPage: 5, want: [][]string{{"a", "b", "", "c", "d"}, {"e"}}},
+		{name: "keeps paragraphs together", perPage: 4, want: [][]string{{"a", "b"}, {"c", "d", "", "e"}}},
+		{name: "no blank line to break at", perPage: 1, want: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := textPages(lines, tt.perPage)
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("Expected pages %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if got := textPages(nil, 10); len(got) != 1 {
+		t.Errorf("Expected an empty text to have one page, got %d", len(got))
+	}
+}
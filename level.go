@@ -22,6 +22,15 @@ var (
 		5,  // level 17
 		4,  // level 18
 		4,  // level 19
-		3,  // level 20+
+		3,  // level 20
+		3,  // level 21
+		3,  // level 22
+		3,  // level 23
+		2,  // level 24
+		2,  // level 25
+		2,  // level 26
+		2,  // level 27
+		2,  // level 28
+		1,  // level 29+
 	}
 )
\ No newline at end of file
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 )
 
 const (
@@ -12,54 +16,230 @@ const (
 	screenH        = 240
 	rows           = 24
 	cols           = 10
-	tileSize       = 9
+	ticksPerSecond = 60
 )
 
 type Game struct {
-	board        *Board
+	scenes       []Scene
 	inputHandler *InputHandler
 	renderer     *Renderer
+	settings     *Settings
+	highScores   *HighScores
+	botWeights   BotWeights
+	showStats    bool   // the statistics page is shown instead of the score
+	historyDir   string // sessions are logged here, unless it is empty
+	themes       *Themes
+	// spectators receive every game played, when broadcasting is on
+	spectators *SpectateServer
+	quit       bool
 }
 
 func NewGame() *Game {
 	g := &Game{
-		inputHandler: &InputHandler{},
-		renderer:     NewRenderer(tileSize, rows, cols),
+		renderer: NewRenderer(rows, cols),
 	}
+
+	path, err := configFilePath("settings.json")
+	if err != nil {
+		log.Printf("settings will not be saved: %v", err)
+	}
+	g.settings, err = LoadSettings(path)
+	if err != nil {
+		log.Printf("could not load settings: %v", err)
+	}
+
+	path, err = configFilePath("highscores.json")
+	if err != nil {
+		log.Printf("high scores will not be saved: %v", err)
+	}
+	g.highScores, err = LoadHighScores(path)
+	if err != nil {
+		log.Printf("could not load high scores: %v", err)
+	}
+
+	path, err = configFilePath("bot_weights.json")
+	if err == nil {
+		g.botWeights, err = LoadBotWeights(path)
+	}
+	if err != nil {
+		g.botWeights = defaultBotWeights
+		log.Printf("could not load bot weights: %v", err)
+	}
+
+	path, err = configFilePath("skins")
+	if err == nil {
+		err = loadSpriteSkins(path)
+	}
+	if err != nil {
+		log.Printf("could not load skins: %v", err)
+	}
+
+	path, err = configFilePath("themes")
+	if err != nil {
+		log.Printf("only the built-in themes are available: %v", err)
+	}
+	g.themes, err = NewThemes(path)
+	if err != nil {
+		log.Printf("could not load themes: %v", err)
+	}
+
+	g.historyDir, err = configFilePath("history")
+	if err != nil {
+		log.Printf("sessions will not be logged: %v", err)
+	}
+
+	g.inputHandler = NewInputHandler(g.settings)
+	g.renderer.settings = g.settings
+	g.scenes = []Scene{newTitleScene(g)}
+
 	return g
 }
 
 func (g *Game) Update() error {
-	// Global input handling (creating a new game)
-	if (g.board == nil || g.board.gameOver) && inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-		g.board = NewBoard(rows, cols)
+	if _, err := g.themes.Poll(time.Now()); err != nil {
+		log.Printf("could not reload themes: %v", err)
 	}
 
-	// Delegate board-related input to the handler
-	g.inputHandler.Update(g.board)
+	if g.inputHandler.FullscreenPressed() {
+		g.toggleFullscreen()
+		if err := g.settings.Save(); err != nil {
+			log.Printf("could not save settings: %v", err)
+		}
+	}
+
+	// Only the top scene gets the input and updates
+	if err := g.scenes[len(g.scenes)-1].Update(g); err != nil {
+		return err
+	}
 
-	// Update game state
-	if g.board != nil {
-		g.board.Tick()
+	if g.quit {
+		return ebiten.Termination
 	}
 
 	return nil
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	// Delegate all drawing to the renderer
-	g.renderer.Draw(screen, g.board)
+	applyTheme(g.themes.Named(g.settings.Theme))
+	palette = paletteNamed(g.settings.Palette)
+	g.renderer.Place(screen.Bounds())
+
+	for _, scene := range g.scenes {
+		scene.Draw(g, screen)
+	}
 }
 
+func (g *Game) pushScene(s Scene) {
+	g.scenes = append(g.scenes, s)
+}
+
+func (g *Game) popScene() {
+	if len(g.scenes) > 1 {
+		g.scenes = g.scenes[:len(g.scenes)-1]
+	}
+}
+
+func (g *Game) toggleFullscreen() {
+	g.settings.Fullscreen = !g.settings.Fullscreen
+	ebiten.SetFullscreen(g.settings.Fullscreen)
+}
+
+// quitToTitle drops every scene above the title screen.
+func (g *Game) quitToTitle() {
+	g.scenes = g.scenes[:1]
+}
+
+// startGame replaces everything above the title screen with a new game.
+func (g *Game) startGame(mode Mode) {
+	g.quitToTitle()
+	g.pushScene(newPlayScene(g, mode))
+}
+
+// startVersus replaces everything above the title screen with a new local
+// versus match.
+func (g *Game) startVersus(bestOf int) {
+	g.quitToTitle()
+	g.pushScene(newVersusScene(g, bestOf))
+}
+
+// Layout makes the screen as big as the window allows, in device pixels so
+// pixel perfect scaling stays exact on high DPI displays.
 func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenW, screenH
+	scale := 1.0
+	if m := ebiten.Monitor(); m != nil {
+		scale = m.DeviceScaleFactor()
+	}
+
+	return screenSize(int(float64(outsideWidth)*scale), int(float64(outsideHeight)*scale), g.settings.PixelPerfect)
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string) error{
+			"server":  runServerCommand,
+			"tune":    runTuneCommand,
+			"history": runHistoryCommand,
+		}
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	connect := flag.String("connect", "", "join an online versus match at host:port")
+	broadcast := flag.String("broadcast", "", "stream games to spectators on this address, e.g. :8080")
+	spectate := flag.String("spectate", "", "watch a streamed game, e.g. ws://host:8080/spectate")
+	tbp := flag.String("tbp", "", "watch an external Tetris Bot Protocol bot play, e.g. \"cold-clear --tbp\"")
+	tui := flag.Bool("tui", false, "play in the terminal instead of a window")
+	mode := flag.String("mode", marathonMode, "the mode to play with -tui, e.g. sprint-40 or ultra-120")
+	flag.Parse()
+
+	if *tui {
+		if err := runTUI(*mode); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	ebiten.SetWindowSize(1024, 768)
 	ebiten.SetWindowTitle("Mletris")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+
+	game := NewGame()
+	ebiten.SetFullscreen(game.settings.Fullscreen)
+	var driver *TBPDriver
+	switch {
+	case *connect != "":
+		game.pushScene(newOnlineScene(game, *connect))
+	case *spectate != "":
+		game.pushScene(newSpectatorScene(*spectate))
+	case *tbp != "":
+		command := strings.Fields(*tbp)
+		bot, err := StartTBPBot(command[0], command[1:]...)
+		if err != nil {
+			log.Fatalf("could not start bot: %v", err)
+		}
+		driver = NewTBPDriver(bot)
+		game.pushScene(newAttractScene(NewTBPBotInput(game.settings, driver)))
+	}
 
-	if err := ebiten.RunGame(NewGame()); err != nil {
+	if *broadcast != "" {
+		game.spectators = NewSpectateServer()
+		mux := http.NewServeMux()
+		mux.Handle("/spectate", game.spectators)
+		go func() {
+			log.Fatal(http.ListenAndServe(*broadcast, mux))
+		}()
+	}
+
+	err := ebiten.RunGame(game)
+	// log.Fatal skips deferred calls, so the bot is stopped first
+	if driver != nil {
+		driver.Close()
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+package main
+
+type MenuAction int
+
+const (
+	menuNone MenuAction = iota
+	menuUp
+	menuDown
+	menuLeft
+	menuRight
+	menuConfirm
+	menuBack
+)
+
+// MenuItem is a single line of a menu. Items without Select and Adjust are
+// headers and can't be selected.
+type MenuItem struct {
+	Label  string
+	Value  func() string
+	Select func()
+	Adjust func(delta int)
+}
+
+func (item MenuItem) selectable() bool {
+	return item.Select != nil || item.Adjust != nil
+}
+
+type Menu struct {
+	Title    string
+	Items    []MenuItem
+	selected int
+}
+
+func NewMenu(title string, items ...MenuItem) *Menu {
+	m := &Menu{
+		Title: title,
+		Items: items,
+	}
+	m.Reset()
+
+	return m
+}
+
+// Reset moves the selection back to the first selectable item.
+func (m *Menu) Reset() {
+	m.selected = -1
+	m.move(1)
+}
+
+// Handle applies a menu action and returns true when the menu should be
+// closed.
+func (m *Menu) Handle(action MenuAction) bool {
+	switch action {
+	case menuUp:
+		m.move(-1)
+	case menuDown:
+		m.move(1)
+	case menuLeft, menuRight:
+		delta := 1
+		if action == menuLeft {
+			delta = -1
+		}
+		if item := m.Items[m.selected]; item.Adjust != nil {
+			item.Adjust(delta)
+		}
+	case menuConfirm:
+		item := m.Items[m.selected]
+		if item.Select != nil {
+			item.Select()
+		} else if item.Adjust != nil {
+			item.Adjust(1)
+		}
+	case menuBack:
+		return true
+	}
+
+	return false
+}
+
+func (m *Menu) move(delta int) {
+	n := len(m.Items)
+	for i := 1; i <= n; i++ {
+		next := ((m.selected+delta*i)%n + n) % n
+		if m.Items[next].selectable() {
+			m.selected = next
+			return
+		}
+	}
+}
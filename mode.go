@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const countdownTicks = 3 * ticksPerSecond
+
+// HUDItem is a labelled value shown next to the board or on a results screen.
+type HUDItem struct {
+	Label string
+	Value string
+}
+
+// Mode adds the rules of a game mode on top of the standard board: how the
+// game starts, when it ends and what the HUD shows.
+type Mode interface {
+	// ID is the key of the mode's high score table or personal best.
+	ID() string
+	Title() string
+	Setup(b *Board)
+	// Finished reports whether the goal of the mode has been reached.
+	Finished(b *Board) bool
+	// Banner heads the results screen of a finished game.
+	Banner() string
+	HUD(b *Board) []HUDItem
+	// Results are shown on the results screen. Modes without one return nil.
+	Results(b *Board) []HUDItem
+	// RankedByTime modes keep a personal best time instead of a high score
+	// table.
+	RankedByTime() bool
+}
+
+// MarathonGoal ends a marathon once a number of lines or a level has been
+// reached. The zero value is the endless game.
+type MarathonGoal struct {
+	Lines int
+	Level int
+}
+
+func (g MarathonGoal) String() string {
+	switch {
+	case g.Lines > 0:
+		return fmt.Sprintf("%d lines", g.Lines)
+	case g.Level > 0:
+		return fmt.Sprintf("Level %d", g.Level)
+	}
+
+	return "Endless"
+}
+
+// marathonGoals are the goals a marathon can be played to.
+var marathonGoals = []MarathonGoal{
+	{Lines: 150},
+	{Lines: 200},
+	{Level: 15},
+	{Level: 29},
+	{},
+}
+
+const maxStartLevel = 19
+
+// MaxStartLevel returns the highest level a marathon to the goal can start
+// at. A level goal has to be played up to, so the start is below it.
+func (g MarathonGoal) MaxStartLevel() int {
+	if g.Level > 0 {
+		return min(maxStartLevel, g.Level-1)
+	}
+
+	return maxStartLevel
+}
+
+// MarathonMode is the standard game, played until a goal is reached or
+// forever in the endless variant.
+type MarathonMode struct {
+	Goal       MarathonGoal
+	StartLevel int
+}
+
+// ID leaves out the start level: games from every start level share the
+// high score table of their goal, as starting higher is a way to score more.
+func (m MarathonMode) ID() string {
+	switch {
+	case m.Goal.Lines > 0:
+		return fmt.Sprintf("%s-%dl", marathonMode, m.Goal.Lines)
+	case m.Goal.Level > 0:
+		return fmt.Sprintf("%s-lv%d", marathonMode, m.Goal.Level)
+	}
+
+	return marathonMode
+}
+
+func (m MarathonMode) Title() string {
+	if m.Goal == (MarathonGoal{}) {
+		return "MARATHON"
+	}
+
+	return "MARATHON " + strings.ToUpper(m.Goal.String())
+}
+
+func (m MarathonMode) Setup(b *Board) {
+	b.SetStartLevel(m.StartLevel)
+}
+
+func (m MarathonMode) Finished(b *Board) bool {
+	switch {
+	case m.Goal.Lines > 0:
+		return b.totalNumberOfLinesCleared >= m.Goal.Lines
+	case m.Goal.Level > 0:
+		return b.Level >= m.Goal.Level
+	}
+
+	return false
+}
+
+func (m MarathonMode) Banner() string {
+	return "VICTORY!"
+}
+
+func (m MarathonMode) HUD(b *Board) []HUDItem {
+	if m.Goal.Lines > 0 {
+		return []HUDItem{
+			{Label: "LINES", Value: fmt.Sprintf("%d/%d", b.totalNumberOfLinesCleared, m.Goal.Lines)},
+		}
+	}
+
+	return []HUDItem{
+		{Label: "LINES", Value: fmt.Sprintf("%d", b.totalNumberOfLinesCleared)},
+	}
+}
+
+func (m MarathonMode) Results(b *Board) []HUDItem {
+	if m.Goal == (MarathonGoal{}) {
+		return nil
+	}
+
+	return []HUDItem{
+		{Label: "SCORE", Value: fmt.Sprintf("%d", b.Score)},
+		{Label: "LINES", Value: fmt.Sprintf("%d", b.totalNumberOfLinesCleared)},
+		{Label: "LEVEL", Value: fmt.Sprintf("%d", b.Level)},
+		{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+	}
+}
+
+func (m MarathonMode) RankedByTime() bool {
+	return false
+}
+
+// sprintLineGoals are the line counts a sprint can be played to.
+var sprintLineGoals = []int{20, 40, 100}
+
+// SprintMode is a race to clear a number of lines as fast as possible.
+type SprintMode struct {
+	Lines int
+}
+
+func (m SprintMode) ID() string {
+	return fmt.Sprintf("sprint-%d", m.Lines)
+}
+
+func (m SprintMode) Title() string {
+	return fmt.Sprintf("SPRINT %dL", m.Lines)
+}
+
+func (m SprintMode) Setup(b *Board) {
+	b.countdown = countdownTicks
+}
+
+func (m SprintMode) Finished(b *Board) bool {
+	return b.totalNumberOfLinesCleared >= m.Lines
+}
+
+func (m SprintMode) Banner() string {
+	return "VICTORY!"
+}
+
+func (m SprintMode) HUD(b *Board) []HUDItem {
+	return []HUDItem{
+		{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+		{Label: "LINES", Value: fmt.Sprintf("%d", max(m.Lines-b.totalNumberOfLinesCleared, 0))},
+	}
+}
+
+func (m SprintMode) Results(b *Board) []HUDItem {
+	return []HUDItem{
+		{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+		{Label: "PIECES", Value: fmt.Sprintf("%d", b.piecesPlaced)},
+		{Label: "PPS", Value: fmt.Sprintf("%.2f", b.PiecesPerSecond())},
+		{Label: "FINESSE", Value: fmt.Sprintf("%d", b.finesse.Faults)},
+	}
+}
+
+func (m SprintMode) RankedByTime() bool {
+	return true
+}
+
+// ultraTimeLimits are the lengths of an Ultra game in seconds.
+var ultraTimeLimits = []int{60, 120, 180, 300}
+
+// UltraMode is a score attack against the clock. The clock is the board's
+// tick count, so it stops while the game is paused.
+type UltraMode struct {
+	Seconds int
+	// LevelCurve lets the level go up every 10 lines. Otherwise it stays
+	// at 0 for the whole game.
+	LevelCurve bool
+}
+
+func (m UltraMode) ID() string {
+	if m.LevelCurve {
+		return fmt.Sprintf("ultra-%d-curve", m.Seconds)
+	}
+
+	return fmt.Sprintf("ultra-%d", m.Seconds)
+}
+
+func (m UltraMode) Title() string {
+	title := fmt.Sprintf("ULTRA %s", formatDuration(time.Duration(m.Seconds)*time.Second))
+	if m.LevelCurve {
+		title += " CURVE"
+	}
+
+	return title
+}
+
+func (m UltraMode) Setup(b *Board) {
+	b.countdown = countdownTicks
+	b.levelFrozen = !m.LevelCurve
+}
+
+func (m UltraMode) Finished(b *Board) bool {
+	return b.ticksPlayed >= m.timeLimit()
+}
+
+func (m UltraMode) Banner() string {
+	return "TIME UP"
+}
+
+func (m UltraMode) HUD(b *Board) []HUDItem {
+	return []HUDItem{
+		{Label: "TIME", Value: formatTicks(max(m.timeLimit()-b.ticksPlayed, 0))},
+		{Label: "LINES", Value: fmt.Sprintf("%d", b.totalNumberOfLinesCleared)},
+	}
+}
+
+func (m UltraMode) Results(b *Board) []HUDItem {
+	return []HUDItem{
+		{Label: "SCORE", Value: fmt.Sprintf("%d", b.Score)},
+		{Label: "LINES", Value: fmt.Sprintf("%d", b.totalNumberOfLinesCleared)},
+		{Label: "PIECES", Value: fmt.Sprintf("%d", b.piecesPlaced)},
+		{Label: "PPS", Value: fmt.Sprintf("%.2f", b.PiecesPerSecond())},
+	}
+}
+
+func (m UltraMode) RankedByTime() bool {
+	return false
+}
+
+func (m UltraMode) timeLimit() int {
+	return m.Seconds * ticksPerSecond
+}
+
+// digRowCounts are the numbers of cheese rows a dig race can start with.
+var digRowCounts = []int{10, 12, 14, 16, 18}
+
+// DigMode starts with rows of cheese garbage, each with a single random
+// hole. The race ends when all of it has been cleared. The endless variant
+// keeps refilling the garbage and is played until the player tops out.
+type DigMode struct {
+	Rows    int
+	Endless bool
+}
+
+func (m DigMode) ID() string {
+	if m.Endless {
+		return fmt.Sprintf("dig-endless-%d", m.Rows)
+	}
+
+	return fmt.Sprintf("dig-%d", m.Rows)
+}
+
+func (m DigMode) Title() string {
+	if m.Endless {
+		return fmt.Sprintf("DIG ENDLESS %d", m.Rows)
+	}
+
+	return fmt.Sprintf("DIG %d", m.Rows)
+}
+
+func (m DigMode) Setup(b *Board) {
+	b.countdown = countdownTicks
+	b.garbage = GarbageSettings{Rule: GarbageHoleRandom}
+	b.AddGarbage(m.Rows)
+
+	if m.Endless {
+		b.garbageRefill = m.Rows
+	}
+}
+
+func (m DigMode) Finished(b *Board) bool {
+	return !m.Endless && b.GarbageRowsLeft() == 0
+}
+
+func (m DigMode) Banner() string {
+	return "VICTORY!"
+}
+
+func (m DigMode) HUD(b *Board) []HUDItem {
+	if m.Endless {
+		return []HUDItem{
+			{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+			{Label: "DUG", Value: fmt.Sprintf("%d", b.garbageCleared)},
+		}
+	}
+
+	return []HUDItem{
+		{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+		{Label: "GARBAGE", Value: fmt.Sprintf("%d", b.GarbageRowsLeft())},
+	}
+}
+
+func (m DigMode) Results(b *Board) []HUDItem {
+	return []HUDItem{
+		{Label: "TIME", Value: formatTicks(b.ticksPlayed)},
+		{Label: "PIECES", Value: fmt.Sprintf("%d", b.piecesPlaced)},
+		{Label: "DUG", Value: fmt.Sprintf("%d", b.garbageCleared)},
+		{Label: "PPS", Value: fmt.Sprintf("%.2f", b.PiecesPerSecond())},
+	}
+}
+
+// RankedByTime is true for the race. The endless variant keeps a high score
+// table.
+func (m DigMode) RankedByTime() bool {
+	return !m.Endless
+}
+
+// playTick moves a game on by a tick and checks the goal of its mode. The
+// window and the terminal both play through it.
+func playTick(b *Board, mode Mode) {
+	b.Tick()
+	if mode.Finished(b) {
+		b.finished = true
+	}
+}
+
+// modeByID returns the mode with an ID.
+func modeByID(id string) (Mode, bool) {
+	for _, m := range rankedModes() {
+		if m.ID() == id {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+// rankedModes lists every mode with its own high score table or personal
+// best, in the order the high score screen shows them.
+func rankedModes() []Mode {
+	var modes []Mode
+	for _, goal := range marathonGoals {
+		modes = append(modes, MarathonMode{Goal: goal})
+	}
+	for _, lines := range sprintLineGoals {
+		modes = append(modes, SprintMode{Lines: lines})
+	}
+	for _, curve := range []bool{false, true} {
+		for _, seconds := range ultraTimeLimits {
+			modes = append(modes, UltraMode{Seconds: seconds, LevelCurve: curve})
+		}
+	}
+	for _, endless := range []bool{false, true} {
+		for _, cheese := range digRowCounts {
+			modes = append(modes, DigMode{Rows: cheese, Endless: endless})
+		}
+	}
+
+	return modes
+}
+
+// cycle returns the value delta steps away from value in values, wrapping
+// around at both ends.
+func cycle[T comparable](values []T, value T, delta int) T {
+	for i, v := range values {
+		if v == value {
+			n := len(values)
+			return values[((i+delta)%n+n)%n]
+		}
+	}
+
+	return values[0]
+}
+
+// formatTicks prints a frame counted time as m:ss.cc.
+func formatTicks(ticks int) string {
+	centiseconds := ticks * 100 / ticksPerSecond
+	return fmt.Sprintf("%d:%02d.%02d", centiseconds/6000, centiseconds/100%60, centiseconds%100)
+}
+
+// formatDuration prints a play time as m:ss.
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"io"
+	"net"
+	"sync"
+)
+
+// protocolVersion must match between the server and every client. Bump it
+// whenever the messages or the game rules change.
+const protocolVersion = 1
+
+const (
+	defaultInputDelay = 3  // frames between reading an input and playing it
+	hashInterval      = 60 // frames between board hash checks
+	defaultNetPort    = "7777"
+)
+
+const (
+	msgHello   = "hello"
+	msgWelcome = "welcome"
+	msgStart   = "start"
+	msgInput   = "input"
+	msgHash    = "hash"
+	msgDesync  = "desync"
+	msgLeft    = "left"
+	msgError   = "error"
+)
+
+var ErrVersionMismatch = errors.New("protocol version mismatch")
+
+// Handling is the part of a player's settings the other clients need to
+// simulate their inputs.
+type Handling struct {
+	DAS int `json:"das"`
+	ARR int `json:"arr"`
+}
+
+// NetMessage is a single message of the versus protocol. Messages are sent
+// as one JSON object per line over TCP.
+type NetMessage struct {
+	Type     string     `json:"type"`
+	Version  int        `json:"version,omitempty"`
+	Player   int        `json:"player"`
+	Players  int        `json:"players,omitempty"`
+	Seed     int64      `json:"seed,omitempty"`
+	Delay    int        `json:"delay,omitempty"`
+	Handling []Handling `json:"handling,omitempty"`
+	Frame    int        `json:"frame,omitempty"`
+	Buttons  Buttons    `json:"buttons,omitempty"`
+	Hash     uint64     `json:"hash,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// netConn is a connection speaking the versus protocol. Sends are safe to
+// call from several goroutines.
+type netConn struct {
+	conn net.Conn
+	dec  *json.Decoder
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newNetConn(conn net.Conn) *netConn {
+	return &netConn{
+		conn: conn,
+		dec:  json.NewDecoder(bufio.NewReader(conn)),
+		enc:  json.NewEncoder(conn),
+	}
+}
+
+func (c *netConn) send(msg NetMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.enc.Encode(msg)
+}
+
+func (c *netConn) receive() (NetMessage, error) {
+	var msg NetMessage
+	err := c.dec.Decode(&msg)
+
+	return msg, err
+}
+
+func (c *netConn) Close() error {
+	return c.conn.Close()
+}
+
+// NetClient is a connection to a versus server that has joined a match.
+type NetClient struct {
+	conn  *netConn
+	Start NetMessage
+	// Player is the index of this client's board.
+	Player int
+
+	incoming  chan NetMessage
+	done      chan struct{} // closed when the read loop stops
+	closed    chan struct{} // closed by Close
+	closeOnce sync.Once
+	err       error
+}
+
+// DialNetClient connects to a server and waits until the match starts.
+func DialNetClient(addr string, handling Handling) (*NetClient, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &NetClient{
+		conn:     newNetConn(conn),
+		incoming: make(chan NetMessage, 256),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+
+	if err := c.handshake(handling); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+
+	return c, nil
+}
+
+func (c *NetClient) handshake(handling Handling) error {
+	hello := NetMessage{Type: msgHello, Version: protocolVersion, Handling: []Handling{handling}}
+	if err := c.conn.send(hello); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.conn.receive()
+		if err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case msgWelcome:
+			c.Player = msg.Player
+		case msgStart:
+			c.Start = msg
+			return nil
+		case msgError:
+			return fmt.Errorf("server: %s", msg.Error)
+		default:
+			return fmt.Errorf("unexpected %q message before the match started", msg.Type)
+		}
+	}
+}
+
+func (c *NetClient) readLoop() {
+	defer close(c.done)
+
+	for {
+		msg, err := c.conn.receive()
+		if err != nil {
+			c.err = err
+			return
+		}
+
+		// Nobody reads the messages after Close
+		select {
+		case c.incoming <- msg:
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *NetClient) Send(msg NetMessage) error {
+	msg.Player = c.Player
+	return c.conn.send(msg)
+}
+
+func (c *NetClient) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.conn.Close()
+}
+
+// LockstepSession simulates every board of an online match. Each client only
+// sends its own buttons; a frame is played once the buttons of all players
+// for it have arrived, so all clients play exactly the same game.
+type LockstepSession struct {
+	client      *NetClient
+	Boards      []*Board
+	controllers []*Controller
+	delay       int
+
+	frame      int // next frame to simulate
+	inputFrame int // next frame to send the local input for
+	inputs     map[int]map[int]Buttons
+
+	Desynced    bool
+	DesyncFrame int
+	Err         error
+}
+
+func NewLockstepSession(c *NetClient) *LockstepSession {
+	start := c.Start
+	s := &LockstepSession{
+		client:     c,
+		delay:      start.Delay,
+		inputFrame: start.Delay,
+		inputs:     map[int]map[int]Buttons{},
+	}
+
+	for i := 0; i < start.Players; i++ {
+		settings := DefaultSettings()
+		if i < len(start.Handling) {
+			settings.DAS = start.Handling[i].DAS
+			settings.ARR = start.Handling[i].ARR
+			settings.clamp()
+		}
+
+		s.Boards = append(s.Boards, NewBoardWithSeed(rows, cols, start.Seed))
+		s.controllers = append(s.controllers, NewController(settings))
+	}
+
+	// Nobody has pressed anything during the first frames of input delay
+	for frame := 0; frame < s.delay; frame++ {
+		for player := range s.Boards {
+			s.setInput(frame, player, 0)
+		}
+	}
+
+	return s
+}
+
+// Frame returns the number of frames played so far.
+func (s *LockstepSession) Frame() int {
+	return s.frame
+}
+
+// SendInput sends the buttons the local player holds. They are played
+// after the input delay. Nothing is sent while the session waits for the
+// other players, so the local input never runs too far ahead.
+func (s *LockstepSession) SendInput(buttons Buttons) {
+	if s.inputFrame > s.frame+s.delay {
+		return
+	}
+
+	s.setInput(s.inputFrame, s.client.Player, buttons)
+	err := s.client.Send(NetMessage{Type: msgInput, Frame: s.inputFrame, Buttons: buttons})
+	if err != nil && s.Err == nil {
+		s.Err = err
+	}
+	s.inputFrame++
+}
+
+// Step reads the messages that have arrived and plays the next frame when
+// all inputs for it are known. It reports whether a frame was played.
+func (s *LockstepSession) Step() bool {
+	s.poll()
+
+	inputs := s.inputs[s.frame]
+	if s.Err != nil || len(inputs) < len(s.Boards) {
+		return false
+	}
+	delete(s.inputs, s.frame)
+
+	for i, b := range s.Boards {
+		s.controllers[i].Apply(b, inputs[i])
+		b.Tick()
+	}
+	s.routeGarbage()
+
+	s.frame++
+	if s.frame%hashInterval == 0 {
+		err := s.client.Send(NetMessage{Type: msgHash, Frame: s.frame, Hash: s.Hash()})
+		if err != nil && s.Err == nil {
+			s.Err = err
+		}
+	}
+
+	return true
+}
+
+func (s *LockstepSession) poll() {
+	for {
+		select {
+		case msg := <-s.client.incoming:
+			s.handle(msg)
+		case <-s.client.done:
+			// Messages that arrived before the connection closed still count
+			for len(s.client.incoming) > 0 {
+				s.handle(<-s.client.incoming)
+			}
+			if s.Err == nil && s.client.err != nil {
+				s.Err = s.client.err
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *LockstepSession) handle(msg NetMessage) {
+	switch msg.Type {
+	case msgInput:
+		if msg.Player >= 0 && msg.Player < len(s.Boards) {
+			s.setInput(msg.Frame, msg.Player, msg.Buttons)
+		}
+	case msgDesync:
+		s.Desynced = true
+		s.DesyncFrame = msg.Frame
+	case msgLeft:
+		if msg.Player >= 0 && msg.Player < len(s.Boards) && s.Err == nil {
+			s.Err = fmt.Errorf("player %d left the game", msg.Player+1)
+		}
+	case msgError:
+		if s.Err == nil {
+			s.Err = fmt.Errorf("server: %s", msg.Error)
+		}
+	}
+}
+
+func (s *LockstepSession) setInput(frame, player int, buttons Buttons) {
+	if s.inputs[frame] == nil {
+		s.inputs[frame] = map[int]Buttons{}
+	}
+	s.inputs[frame][player] = buttons
+}
+
+// routeGarbage sends the attack of every board to the next player still
+// in the game.
+func (s *LockstepSession) routeGarbage() {
+	for i, b := range s.Boards {
+		lines := b.TakeOutgoingGarbage()
+		if lines == 0 {
+			continue
+		}
+
+		for n := 1; n < len(s.Boards); n++ {
+			target := s.Boards[(i+n)%len(s.Boards)]
+			if !target.gameOver {
+				target.QueueGarbage(lines)
+				break
+			}
+		}
+	}
+}
+
+// Over reports whether at most one player is left. Winner is the index of
+// that player, or -1 when everybody topped out.
+func (s *LockstepSession) Over() (over bool, winner int) {
+	winner = -1
+	alive := 0
+	for i, b := range s.Boards {
+		if !b.gameOver {
+			alive++
+			winner = i
+		}
+	}
+
+	if alive > 1 || (alive == 1 && len(s.Boards) == 1) {
+		return false, -1
+	}
+
+	return true, winner
+}
+
+// Hash returns a checksum of the state of all boards. Clients that played
+// the same inputs get the same hash.
+func (s *LockstepSession) Hash() uint64 {
+	h := fnv.New64a()
+	for _, b := range s.Boards {
+		b.writeHash(h)
+	}
+
+	return h.Sum64()
+}
+
+func (s *LockstepSession) Close() error {
+	return s.client.Close()
+}
+
+// writeHash writes everything that decides how the game goes on.
+func (b *Board) writeHash(h io.Writer) {
+	var buf [8]byte
+	write := func(values ...int) {
+		for _, v := range values {
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			h.Write(buf[:])
+		}
+	}
+
+	for y := range b.field.Height() {
+		for x := range b.field.Width() {
+			write(colorKey(b.field.Color(x, y)))
+		}
+	}
+
+	p := b.currentPiece
+	write(int(p.piece.kind), p.state, int(p.x), int(p.y))
+	for _, next := range b.pieceQueue {
+		write(int(next.piece.kind))
+	}
+
+	write(b.Score, b.Level, b.totalNumberOfLinesCleared, b.pendingGarbage, b.combo, b.tickNumber)
+	if b.gameOver {
+		write(1)
+	}
+}
+
+func colorKey(c color.Color) int {
+	if c == nil {
+		return -1
+	}
+
+	r, g, bl, a := c.RGBA()
+	return int(r>>8)<<24 | int(g>>8)<<16 | int(bl>>8)<<8 | int(a>>8)
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startTestServer runs a match server on a free loopback port.
+func startTestServer(t *testing.T, players int) *NetServer {
+	t.Helper()
+
+	server, err := NewNetServer("127.0.0.1:0", players, defaultInputDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	return server
+}
+
+// joinTestMatch connects all players at once, as the server only starts the
+// match when everybody is in.
+func joinTestMatch(t *testing.T, addr string, players int) []*LockstepSession {
+	t.Helper()
+
+	sessions := make([]*LockstepSession, players)
+	errs := make([]error, players)
+
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := DialNetClient(addr, Handling{DAS: 8 + i, ARR: 1 + i})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			sessions[i] = NewLockstepSession(client)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, s := range sessions {
+			s.Close()
+		}
+	})
+
+	return sessions
+}
+
+// scriptedButtons is a made up player that moves, rotates and drops pieces.
+func scriptedButtons(player, frame int) Buttons {
+	switch (frame + player*7) % 40 {
+	case 1, 2, 3:
+		return ButtonLeft << player
+	case 10:
+		return ButtonRotate
+	case 20, 21, 22, 23, 24:
+		return ButtonRight
+	case 30:
+		return ButtonHardDrop
+	}
+
+	return 0
+}
+
+// playFrames plays a session up to a frame as fast as the other clients allow.
+func playFrames(s *LockstepSession, frames int) error {
+	deadline := time.Now().Add(10 * time.Second)
+
+	for s.Frame() < frames {
+		s.SendInput(scriptedButtons(s.client.Player, s.inputFrame))
+		if !s.Step() {
+			if s.Err != nil {
+				return s.Err
+			}
+			if time.Now().After(deadline) {
+				return errors.New("timed out waiting for inputs")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	return nil
+}
+
+func playAll(t *testing.T, sessions []*LockstepSession, frames int) {
+	t.Helper()
+
+	errs := make([]error, len(sessions))
+	var wg sync.WaitGroup
+	for i, s := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = playFrames(s, frames)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLockstep_ClientsStayInSync(t *testing.T) {
+	server := startTestServer(t, 2)
+	sessions := joinTestMatch(t, server.Addr(), 2)
+
+	frames := 10 * hashInterval
+	playAll(t, sessions, frames)
+
+	if sessions[0].client.Player == sessions[1].client.Player {
+		t.Fatalf("both clients got player %d", sessions[0].client.Player)
+	}
+
+	if sessions[0].Hash() != sessions[1].Hash() {
+		t.Errorf("clients ended with different boards")
+	}
+
+	for i, s := range sessions {
+		if s.Desynced {
+			t.Errorf("client %d saw a desync at frame %d", i, s.DesyncFrame)
+		}
+		if s.Boards[0].piecesPlaced == 0 || s.Boards[1].piecesPlaced == 0 {
+			t.Errorf("client %d: the scripted players placed no pieces", i)
+		}
+	}
+}
+
+func TestLockstep_DetectsDesync(t *testing.T) {
+	server := startTestServer(t, 2)
+	sessions := joinTestMatch(t, server.Addr(), 2)
+
+	playAll(t, sessions, hashInterval/2)
+
+	// Something only one client knows about
+	sessions[1].Boards[0].Score += 100
+
+	playAll(t, sessions, 2*hashInterval)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, s := range sessions {
+		for !s.Desynced && time.Now().Before(deadline) {
+			s.poll()
+			time.Sleep(time.Millisecond)
+		}
+
+		if !s.Desynced {
+			t.Fatalf("client %d did not hear about the desync", s.client.Player)
+		}
+		if s.DesyncFrame != hashInterval {
+			t.Errorf("desync reported at frame %d, want %d", s.DesyncFrame, hashInterval)
+		}
+	}
+}
+
+func TestNetServer_RejectsOtherVersions(t *testing.T) {
+	server := startTestServer(t, 2)
+
+	conn, err := net.Dial("tcp", server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newNetConn(conn)
+	defer c.Close()
+
+	c.send(NetMessage{Type: msgHello, Version: protocolVersion + 1})
+
+	msg, err := c.receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != msgError {
+		t.Errorf("got %q message, want %q", msg.Type, msgError)
+	}
+}
+
+func TestNetServer_DropsSilentConnections(t *testing.T) {
+	server, err := NewNetServer("127.0.0.1:0", 2, defaultInputDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.handshake = 50 * time.Millisecond
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+
+	// Connects and never says hello
+	silent, err := net.Dial("tcp", server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	sessions := joinTestMatch(t, server.Addr(), 2)
+	playAll(t, sessions, hashInterval)
+}
+
+// dialTestPlayer joins a match by hand.
+func dialTestPlayer(t *testing.T, addr string) *netConn {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newNetConn(conn)
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.send(NetMessage{Type: msgHello, Version: protocolVersion}); err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+// receiveType reads messages until one of a type arrives.
+func receiveType(t *testing.T, c *netConn, msgType string) NetMessage {
+	t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		msg, err := c.receive()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestNetServer_ChecksHashesOfPlayersLeft(t *testing.T) {
+	server := startTestServer(t, 3)
+
+	conns := make([]*netConn, 3)
+	for i := range conns {
+		conns[i] = dialTestPlayer(t, server.Addr())
+	}
+	for _, c := range conns {
+		receiveType(t, c, msgStart)
+	}
+
+	// One player sends a hash and leaves before the others send theirs
+	conns[2].send(NetMessage{Type: msgHash, Frame: 0, Hash: 1})
+	conns[2].Close()
+	receiveType(t, conns[0], msgLeft)
+	receiveType(t, conns[1], msgLeft)
+
+	for frame := 0; frame < 10*hashInterval; frame += hashInterval {
+		conns[0].send(NetMessage{Type: msgHash, Frame: frame, Hash: 1})
+		conns[1].send(NetMessage{Type: msgHash, Frame: frame, Hash: 1})
+	}
+	conns[0].send(NetMessage{Type: msgHash, Frame: 10 * hashInterval, Hash: 1})
+	conns[1].send(NetMessage{Type: msgHash, Frame: 10 * hashInterval, Hash: 2})
+
+	if msg := receiveType(t, conns[0], msgDesync); msg.Frame != 10*hashInterval {
+		t.Errorf("desync reported at frame %d, want %d", msg.Frame, 10*hashInterval)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.hashes) != 0 {
+		t.Errorf("expected no frames left waiting for hashes, got %d", len(server.hashes))
+	}
+}
+
+func TestNetClient_CloseStopsReading(t *testing.T) {
+	local, remote := net.Pipe()
+	c := &NetClient{
+		conn:     newNetConn(local),
+		incoming: make(chan NetMessage, 1),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go c.readLoop()
+
+	// More messages than fit, and nobody polls them
+	server := newNetConn(remote)
+	go func() {
+		for frame := range 10 {
+			if server.send(NetMessage{Type: msgInput, Frame: frame}) != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	c.Close()
+	select {
+	case <-c.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the read loop is still blocked after Close")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// handshakeTimeout is how long a new connection has to say hello, so one
+// that never does can't keep other players from joining.
+const handshakeTimeout = 5 * time.Second
+
+// NetServer runs a single online versus match. It waits for all players,
+// tells them the seed, then relays every player's inputs to the others and
+// compares their board hashes to detect desyncs.
+type NetServer struct {
+	listener  net.Listener
+	players   int
+	delay     int
+	handshake time.Duration
+
+	mu        sync.Mutex
+	conns     []*netConn
+	connected int // players who haven't left the match
+	hashes    map[int][]uint64
+	// Desyncs counts the hash checks that didn't match.
+	Desyncs int
+}
+
+func NewNetServer(addr string, players, delay int) (*NetServer, error) {
+	if players < 2 {
+		return nil, errors.New("a match needs at least 2 players")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NetServer{
+		listener:  listener,
+		players:   players,
+		delay:     delay,
+		handshake: handshakeTimeout,
+		hashes:    map[int][]uint64{},
+	}, nil
+}
+
+func (s *NetServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *NetServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.conns {
+		c.Close()
+	}
+
+	return s.listener.Close()
+}
+
+// Serve plays one match and returns once every player has left.
+func (s *NetServer) Serve() error {
+	handling := make([]Handling, 0, s.players)
+
+	for len(s.conns) < s.players {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		c := newNetConn(conn)
+		conn.SetDeadline(time.Now().Add(s.handshake))
+		h, err := s.join(c)
+		if err == nil {
+			err = conn.SetDeadline(time.Time{})
+		}
+		if err != nil {
+			log.Printf("player rejected: %v", err)
+			c.Close()
+			continue
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, c)
+		s.connected++
+		s.mu.Unlock()
+		handling = append(handling, h)
+	}
+
+	start := NetMessage{
+		Type:     msgStart,
+		Players:  s.players,
+		Seed:     rand.Int63(),
+		Delay:    s.delay,
+		Handling: handling,
+	}
+	for _, c := range s.conns {
+		if err := c.send(start); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for player, c := range s.conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.relay(player, c)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// join checks the hello message of a new player and welcomes them.
+func (s *NetServer) join(c *netConn) (Handling, error) {
+	hello, err := c.receive()
+	if err != nil {
+		return Handling{}, err
+	}
+
+	if hello.Type != msgHello {
+		return Handling{}, fmt.Errorf("expected hello, got %q", hello.Type)
+	}
+
+	if hello.Version != protocolVersion {
+		c.send(NetMessage{Type: msgError, Error: fmt.Sprintf("%v: server %d, client %d", ErrVersionMismatch, protocolVersion, hello.Version)})
+		return Handling{}, ErrVersionMismatch
+	}
+
+	h := Handling{DAS: pressDelayTicks, ARR: pressRepeatIntervalTicks}
+	if len(hello.Handling) > 0 {
+		h = hello.Handling[0]
+	}
+
+	return h, c.send(NetMessage{Type: msgWelcome, Player: len(s.conns)})
+}
+
+// relay forwards the messages of one player until they disconnect.
+func (s *NetServer) relay(player int, c *netConn) {
+	for {
+		msg, err := c.receive()
+		if err != nil {
+			s.mu.Lock()
+			s.connected--
+			s.mu.Unlock()
+			s.broadcast(player, NetMessage{Type: msgLeft, Player: player})
+			return
+		}
+
+		msg.Player = player
+		switch msg.Type {
+		case msgInput:
+			s.broadcast(player, msg)
+		case msgHash:
+			s.checkHash(player, msg)
+		}
+	}
+}
+
+// broadcast sends a message to every player except the sender.
+func (s *NetServer) broadcast(sender int, msg NetMessage) {
+	s.mu.Lock()
+	conns := s.conns
+	s.mu.Unlock()
+
+	for player, c := range conns {
+		if player != sender {
+			c.send(msg)
+		}
+	}
+}
+
+// checkHash compares the hashes of a frame once every player still in the
+// match has sent theirs. Older frames are dropped then too: they are
+// waiting for a player who left and would never be compared.
+func (s *NetServer) checkHash(player int, msg NetMessage) {
+	s.mu.Lock()
+
+	hashes := s.hashes[msg.Frame]
+	if hashes == nil {
+		hashes = make([]uint64, 0, s.players)
+	}
+	hashes = append(hashes, msg.Hash)
+	s.hashes[msg.Frame] = hashes
+
+	if len(hashes) < s.connected {
+		s.mu.Unlock()
+		return
+	}
+	for frame := range s.hashes {
+		if frame <= msg.Frame {
+			delete(s.hashes, frame)
+		}
+	}
+
+	desync := false
+	for _, h := range hashes {
+		if h != hashes[0] {
+			desync = true
+		}
+	}
+	if desync {
+		s.Desyncs++
+	}
+	s.mu.Unlock()
+
+	if desync {
+		log.Printf("desync detected at frame %d", msg.Frame)
+		s.broadcast(-1, NetMessage{Type: msgDesync, Frame: msg.Frame})
+	}
+}
+
+// runServerCommand is the "server" subcommand: a small local process that
+// hosts online versus matches one after another.
+func runServerCommand(args []string) error {
+	flags := flag.NewFlagSet("server", flag.ExitOnError)
+	addr := flags.String("listen", ":"+defaultNetPort, "address to listen on")
+	players := flags.Int("players", 2, "number of players in a match")
+	delay := flags.Int("delay", defaultInputDelay, "input delay in frames")
+	flags.Parse(args)
+
+	for {
+		server, err := NewNetServer(*addr, *players, *delay)
+		if err != nil {
+			return err
+		}
+
+		log.Printf("waiting for %d players on %s", *players, server.Addr())
+		err = server.Serve()
+		server.Close()
+		if err != nil {
+			return err
+		}
+		log.Printf("match over")
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// onlineScene plays a versus match against players connected to the same
+// server. Every client simulates all boards from the inputs it receives.
+type onlineScene struct {
+	addr      string
+	connected chan connectResult
+	session   *LockstepSession
+	renderers []*Renderer
+	err       error
+}
+
+type connectResult struct {
+	client *NetClient
+	err    error
+}
+
+func newOnlineScene(g *Game, addr string) *onlineScene {
+	s := &onlineScene{
+		addr:      addr,
+		connected: make(chan connectResult, 1),
+	}
+
+	handling := Handling{DAS: g.settings.DAS, ARR: g.settings.ARR}
+	go func() {
+		client, err := DialNetClient(addr, handling)
+		s.connected <- connectResult{client, err}
+	}()
+
+	return s
+}
+
+func (s *onlineScene) Update(g *Game) error {
+	if g.inputHandler.MenuAction() == menuBack {
+		s.close()
+		g.quitToTitle()
+		return nil
+	}
+
+	if s.session == nil {
+		select {
+		case r := <-s.connected:
+			if r.err != nil {
+				s.err = r.err
+				return nil
+			}
+			s.start(g, r.client)
+		default:
+		}
+		return nil
+	}
+
+	if over, _ := s.session.Over(); over || s.session.Err != nil {
+		return nil
+	}
+
+	s.session.SendInput(g.inputHandler.Buttons())
+	s.session.Step()
+
+	return nil
+}
+
+func (s *onlineScene) start(g *Game, client *NetClient) {
+	s.session = NewLockstepSession(client)
+	g.inputHandler.Reset()
+
+	for range s.session.Boards {
+		r := NewCompactRenderer(rows, cols)
+		r.settings = g.settings
+		s.renderers = append(s.renderers, r)
+	}
+}
+
+func (s *onlineScene) close() {
+	switch {
+	case s.session != nil:
+		s.session.Close()
+	case s.err == nil:
+		// Still connecting: close the client once it has joined
+		go func() {
+			if r := <-s.connected; r.err == nil {
+				r.client.Close()
+			}
+		}()
+	}
+}
+
+func (s *onlineScene) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(bgColor)
+
+	if s.session == nil {
+		status := "Connecting to " + s.addr + "..."
+		if s.err != nil {
+			status = "Could not join: " + s.err.Error()
+		}
+		g.renderer.drawPageText(screen, status, 20, float64(screenH)/2-10, 12)
+		g.renderer.drawPageText(screen, "[Esc] Menu", 20, float64(screenH)/2+10, 12)
+		return
+	}
+
+	// Fit all boards next to each other
+	for i, b := range s.session.Boards {
+		s.renderers[i].Place(splitArea(screen.Bounds(), len(s.session.Boards), i))
+		label := fmt.Sprintf("P%d", i+1)
+		if i == s.session.client.Player {
+			label = "YOU"
+		}
+		s.renderers[i].DrawBoard(screen, b, []HUDItem{{Label: label}})
+	}
+
+	if s.session.Desynced {
+		g.renderer.drawText(screen, fmt.Sprintf("DESYNC AT FRAME %d", s.session.DesyncFrame), 10, float64(screen.Bounds().Dy())-14, 10)
+	}
+
+	if over, winner := s.session.Over(); over {
+		g.renderer.dimScreen(screen)
+		title := "DRAW"
+		switch {
+		case winner == s.session.client.Player:
+			title = "YOU WIN"
+		case winner >= 0:
+			title = fmt.Sprintf("PLAYER %d WINS", winner+1)
+		}
+		g.renderer.drawPageText(screen, title, 40, float64(screenH)/2-30, 20)
+		g.renderer.drawPageText(screen, "[Esc] Menu", 40, float64(screenH)/2+10, 12)
+	} else if s.session.Err != nil {
+		g.renderer.dimScreen(screen)
+		g.renderer.drawPageText(screen, s.session.Err.Error(), 20, float64(screenH)/2-10, 12)
+		g.renderer.drawPageText(screen, "[Esc] Menu", 20, float64(screenH)/2+10, 12)
+	}
+}
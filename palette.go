@@ -0,0 +1,92 @@
+package main
+
+import "image/color"
+
+// paletteOff is the menu name for drawing pieces in the theme's colours.
+const paletteOff = "Off"
+
+// Palette replaces the piece colours of the theme with colours that stay
+// apart for players with a colour vision deficiency. Like themes, palettes
+// are only used for drawing.
+type Palette struct {
+	Name   string
+	Pieces [garbageKind + 1]color.RGBA // by kind, garbage last
+}
+
+// palettes are picked for the colours each deficiency still tells apart:
+// blue against orange for red-green blindness, red against teal for
+// blue-yellow blindness. Neighbouring pieces also differ in lightness.
+var palettes = []*Palette{
+	{
+		// Okabe-Ito
+		Name: "Deuteranopia",
+		Pieces: [...]color.RGBA{
+			PieceI:      {0x56, 0xb4, 0xe9, 0xff}, // sky blue
+			PieceO:      {0xf0, 0xe4, 0x42, 0xff}, // yellow
+			PieceT:      {0xcc, 0x79, 0xa7, 0xff}, // reddish purple
+			PieceS:      {0x00, 0x9e, 0x73, 0xff}, // bluish green
+			PieceZ:      {0xd5, 0x5e, 0x00, 0xff}, // vermilion
+			PieceJ:      {0x00, 0x72, 0xb2, 0xff}, // blue
+			PieceL:      {0xe6, 0x9f, 0x00, 0xff}, // orange
+			garbageKind: {0x80, 0x80, 0x80, 0xff},
+		},
+	},
+	{
+		Name: "Protanopia",
+		Pieces: [...]color.RGBA{
+			PieceI:      {0x64, 0x8f, 0xff, 0xff}, // blue
+			PieceO:      {0xff, 0xb0, 0x00, 0xff}, // gold
+			PieceT:      {0x78, 0x5e, 0xf0, 0xff}, // violet
+			PieceS:      {0xf0, 0xf0, 0xf0, 0xff}, // white
+			PieceZ:      {0xfe, 0x61, 0x00, 0xff}, // orange
+			PieceJ:      {0x1a, 0x3a, 0x8f, 0xff}, // navy
+			PieceL:      {0xdc, 0x26, 0x7f, 0xff}, // magenta
+			garbageKind: {0x80, 0x80, 0x80, 0xff},
+		},
+	},
+	{
+		Name: "Tritanopia",
+		Pieces: [...]color.RGBA{
+			PieceI:      {0x5e, 0xd1, 0xd1, 0xff}, // light teal
+			PieceO:      {0xff, 0xe0, 0xe0, 0xff}, // pale pink
+			PieceT:      {0xd8, 0x1b, 0x60, 0xff}, // crimson
+			PieceS:      {0x00, 0x79, 0x6b, 0xff}, // dark teal
+			PieceZ:      {0xff, 0x57, 0x22, 0xff}, // red
+			PieceJ:      {0x8d, 0x6e, 0x63, 0xff}, // brown
+			PieceL:      {0xf4, 0x8f, 0xb1, 0xff}, // pink
+			garbageKind: {0x80, 0x80, 0x80, 0xff},
+		},
+	},
+}
+
+// paletteNamed returns the palette with the name, or nil to keep the
+// theme's colours.
+func paletteNamed(name string) *Palette {
+	for _, p := range palettes {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// paletteNames returns paletteOff followed by the names of the palettes.
+func paletteNames() []string {
+	names := []string{paletteOff}
+	for _, p := range palettes {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+// TileColor returns the colour to draw a tile of a kind in, falling back
+// to the theme for a nil palette and for tiles that are not pieces.
+func (p *Palette) TileColor(t *Theme, c color.Color, kind int) color.Color {
+	if p != nil && kind >= 0 && kind < len(p.Pieces) {
+		return p.Pieces[kind]
+	}
+
+	return t.TileColor(c, kind)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image/color"
+	"math/rand"
+)
+
+const (
+	particlesPerCell = 3
+	particleGravity  = 0.02 // tiles per tick per tick
+	minParticleLife  = 30
+	maxParticleLife  = 45
+
+	shakeDecay       = 0.85
+	tetrisShake      = 4.0 // pixels
+	maxHardDropShake = 3.0
+)
+
+// Particle is a spark flying off a cleared cell. Positions are in tiles
+// from the top left corner of the field.
+type Particle struct {
+	X, Y   float64
+	VX, VY float64
+	Color  color.Color
+	Kind   int // of the block it came from
+	Age    int
+	Life   int
+}
+
+// Effects are the particles and screen shake of a board. They have their
+// own random source, so they never touch the game's.
+type Effects struct {
+	Particles []Particle
+	shake     float64
+	rand      *rand.Rand
+}
+
+func NewEffects() *Effects {
+	return &Effects{rand: rand.New(rand.NewSource(1))}
+}
+
+// burst sends particles flying from every cell of the cleared rows.
+func (f *Effects) burst(rows []int, colors [][]color.Color, kinds [][]int) {
+	for i, row := range colors {
+		y := rows[i]
+		for x, c := range row {
+			if c == nil {
+				continue
+			}
+			kind := -1
+			if i < len(kinds) {
+				kind = kinds[i][x]
+			}
+			for range particlesPerCell {
+				f.Particles = append(f.Particles, Particle{
+					X:     float64(x) + f.rand.Float64(),
+					Y:     float64(y) + f.rand.Float64(),
+					VX:    (f.rand.Float64() - 0.5) * 0.3,
+					VY:    -f.rand.Float64() * 0.3,
+					Color: c,
+					Kind:  kind,
+					Life:  minParticleLife + f.rand.Intn(maxParticleLife-minParticleLife),
+				})
+			}
+		}
+	}
+}
+
+// Shake starts shaking the board by up to intensity pixels, unless it is
+// already shaking harder.
+func (f *Effects) Shake(intensity float64) {
+	f.shake = max(f.shake, intensity)
+}
+
+// Update moves the particles on by a tick and calms the shake down.
+func (f *Effects) Update() {
+	alive := f.Particles[:0]
+	for _, p := range f.Particles {
+		p.Age++
+		if p.Age >= p.Life {
+			continue
+		}
+		p.X += p.VX
+		p.Y += p.VY
+		p.VY += particleGravity
+		alive = append(alive, p)
+	}
+	f.Particles = alive
+
+	f.shake *= shakeDecay
+	if f.shake < 0.5 {
+		f.shake = 0
+	}
+}
+
+// Offset returns how far to move the board this frame.
+func (f *Effects) Offset() (x, y float64) {
+	if f.shake == 0 {
+		return 0, 0
+	}
+
+	return (f.rand.Float64()*2 - 1) * f.shake, (f.rand.Float64()*2 - 1) * f.shake
+}
+
+// Clear removes every particle and stops the shake.
+func (f *Effects) Clear() {
+	f.Particles = f.Particles[:0]
+	f.shake = 0
+}
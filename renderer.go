@@ -3,8 +3,12 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"image"
 	"image/color"
 	"log"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/examples/resources/fonts"
@@ -14,6 +18,9 @@ import (
 
 var (
 	mplusFaceSource *text.GoTextFaceSource
+	// fontSource is the font of the theme, mplusFaceSource by default.
+	fontSource  *text.GoTextFaceSource
+	fontSources = map[string]*text.GoTextFaceSource{}
 )
 
 func init() {
@@ -22,6 +29,12 @@ func init() {
 		log.Fatal(err)
 	}
 	mplusFaceSource = s
+
+	themes, err := NewThemes("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	applyTheme(themes.Named(defaultTheme))
 }
 
 // Color Palette
@@ -29,110 +42,357 @@ var (
 	bgColor         = color.RGBA{0x1d, 0x0f, 0x2f, 0xff} // Deep dark purple
 	boardBgColor    = color.RGBA{0x2c, 0x1d, 0x40, 0xff} // Slightly lighter purple
 	frameAndTextColor = color.RGBA{0xf4, 0x00, 0xff, 0xff} // Hot pink/magenta
+	hintColor       = color.RGBA{0x80, 0x80, 0x80, 0x80} // Faint grey outline
+	gridColor       = adjustColor(boardBgColor, 0.8)
+
+	// theme is the theme the colours above come from.
+	theme *Theme
+	// palette replaces the theme's piece colours, unless it is nil.
+	palette *Palette
 )
 
+// applyTheme switches the colours and font to the theme's. Fonts that fail
+// to load are reported once, and the built-in font is used instead.
+func applyTheme(t *Theme) {
+	if t == theme {
+		return
+	}
+
+	theme = t
+	bgColor = color.RGBA(t.Background)
+	boardBgColor = color.RGBA(t.Board)
+	frameAndTextColor = color.RGBA(t.Text)
+	gridColor = t.GridColor()
+
+	path := t.FontPath()
+	if path == "" {
+		fontSource = mplusFaceSource
+		return
+	}
+
+	source, ok := fontSources[path]
+	if !ok {
+		source = mplusFaceSource
+		if data, err := os.ReadFile(path); err != nil {
+			log.Printf("could not load the font of theme %s: %v", t.Name, err)
+		} else if source, err = text.NewGoTextFaceSource(bytes.NewReader(data)); err != nil {
+			log.Printf("could not load the font of theme %s: %v", t.Name, err)
+			source = mplusFaceSource
+		}
+		fontSources[path] = source
+	}
+	fontSource = source
+}
+
+// tileColor returns the colour to draw a tile in with the theme and palette.
+func tileColor(c color.Color, kind int) color.Color {
+	return palette.TileColor(theme, c, kind)
+}
+
+// face returns the theme's font at a size, scaled by the theme.
+func face(size float64) *text.GoTextFace {
+	return &text.GoTextFace{Source: fontSource, Size: size * theme.TextScale}
+}
+
 type Renderer struct {
 	tileSize int
 	rows     int
 	cols     int
 
-	// Layout positions
+	// Layout positions, set by Place
+	area       image.Rectangle
 	boardX     float64
 	boardY     float64
 	nextPieceX float64
 	nextPieceY float64
 	scoreX     float64
 	scoreY     float64
+	pageX      float64 // top left of pages of text, e.g. menus
+	pageY      float64
 
 	boardImage     *ebiten.Image
 	nextPieceImage *ebiten.Image
-}
-
-func NewRenderer(tileSize, rows, cols int) *Renderer {
-	boardWidth := cols * tileSize
-	nextPieceWidth := 4 * tileSize
-	scoreWidth := 80
-	padding := 20
 
-	totalWidth := scoreWidth + padding + boardWidth + padding + nextPieceWidth
-	startX := (screenW - totalWidth) / 2
+	// compact renderers share the screen with other boards
+	compact bool
 
-	return &Renderer{
-		tileSize:       tileSize,
-		rows:           rows,
-		cols:           cols,
-		boardImage:     ebiten.NewImage(cols*tileSize, rows*tileSize),
-		nextPieceImage: ebiten.NewImage(4*tileSize, 4*tileSize),
+	settings *Settings
+	animator *Animator // nil until the renderer watches a board
+}
 
-		// Centered Layout Positions
-		scoreX:     float64(startX),
-		scoreY:     10,
-		boardX:     float64(startX + scoreWidth + padding),
-		boardY:     10,
-		nextPieceX: float64(startX + scoreWidth + padding + boardWidth + padding),
-		nextPieceY: 10,
+func NewRenderer(rows, cols int) *Renderer {
+	r := &Renderer{
+		rows:     rows,
+		cols:     cols,
+		settings: DefaultSettings(),
 	}
+	r.Place(image.Rect(0, 0, screenW, screenH))
+
+	return r
 }
 
-func (r *Renderer) Draw(screen *ebiten.Image, board *Board) {
-	screen.Fill(bgColor)
+// NewCompactRenderer creates a renderer for a board that shares the screen
+// with other boards. Place puts it in its part of the screen.
+func NewCompactRenderer(rows, cols int) *Renderer {
+	r := NewRenderer(rows, cols)
+	r.compact = true
 
-	if board == nil {
-		r.renderStartGame(screen)
+	return r
+}
+
+// Place lays the board and its panels out in an area of the screen, with
+// tiles as big as fit. Pages of text are centred in the area.
+func (r *Renderer) Place(area image.Rectangle) {
+	if area == r.area {
 		return
 	}
+	r.area = area
+
+	p := layoutPanels(area, r.rows, r.cols)
+	if p.TileSize != r.tileSize {
+		r.tileSize = p.TileSize
+		r.boardImage = ebiten.NewImage(r.cols*r.tileSize, r.rows*r.tileSize)
+		r.nextPieceImage = ebiten.NewImage(4*r.tileSize, 4*r.tileSize)
+	}
+
+	r.boardX, r.boardY = float64(p.Board.X), float64(p.Board.Y)
+	r.nextPieceX, r.nextPieceY = float64(p.Next.X), float64(p.Next.Y)
+	r.scoreX, r.scoreY = float64(p.Score.X), float64(p.Score.Y)
+
+	page := pageOrigin(area)
+	r.pageX, r.pageY = float64(page.X), float64(page.Y)
+}
 
+func (r *Renderer) Draw(screen *ebiten.Image, board *Board, hud []HUDItem) {
+	screen.Fill(bgColor)
+	r.DrawBoard(screen, board, hud)
+}
+
+// DrawBoard draws a board with its next piece, score and HUD without
+// clearing the screen first.
+func (r *Renderer) DrawBoard(screen *ebiten.Image, board *Board, hud []HUDItem) {
 	r.renderBoard(board, screen)
 	r.renderNextPiece(board, screen)
 	r.renderScore(board, screen)
+	r.renderHUD(hud, screen)
+	r.renderOverlays(board, screen)
+}
 
-	if board.paused {
-		r.renderPauseOverlay(screen)
+// DrawStats draws a board with the statistics page in place of the score
+// and HUD.
+func (r *Renderer) DrawStats(screen *ebiten.Image, board *Board) {
+	screen.Fill(bgColor)
+	r.renderBoard(board, screen)
+	r.renderNextPiece(board, screen)
+	r.renderStats(screen, &board.stats, board.ticksPlayed, r.scoreX, r.scoreY)
+	r.renderOverlays(board, screen)
+}
+
+func (r *Renderer) renderOverlays(board *Board, screen *ebiten.Image) {
+	r.renderLevelBanner(screen)
+
+	if board.countdown > 0 {
+		r.renderCountdown(board, screen)
 	}
 
 	if board.gameOver {
-		r.renderGameOverOverlay(screen)
+		r.renderGameOverOverlay(screen, "GAME OVER")
+	}
+
+	if board.finished {
+		r.renderGameOverOverlay(screen, "FINISHED")
 	}
 }
 
 func (r *Renderer) renderBoard(board *Board, screen *ebiten.Image) {
 	r.boardImage.Fill(boardBgColor)
 
-	gridColor := adjustColor(boardBgColor, 0.8)
-
-	// Draw vertical grid lines
-	for x := 1; x < r.cols; x++ {
-		vector.StrokeLine(r.boardImage, float32(x*r.tileSize), 0, float32(x*r.tileSize), float32(r.rows*r.tileSize), 1, gridColor, false)
-	}
-	// Draw horizontal grid lines
-	for y := 1; y < r.rows; y++ {
-		vector.StrokeLine(r.boardImage, 0, float32(y*r.tileSize), float32(r.cols*r.tileSize), float32(y*r.tileSize), 1, gridColor, false)
+	if r.settings.ShowGrid {
+		// Draw vertical grid lines
+		for x := 1; x < r.cols; x++ {
+			vector.StrokeLine(r.boardImage, float32(x*r.tileSize), 0, float32(x*r.tileSize), float32(r.rows*r.tileSize), 1, gridColor, false)
+		}
+		// Draw horizontal grid lines
+		for y := 1; y < r.rows; y++ {
+			vector.StrokeLine(r.boardImage, 0, float32(y*r.tileSize), float32(r.cols*r.tileSize), float32(y*r.tileSize), 1, gridColor, false)
+		}
 	}
 
 	// Frame
 	vector.StrokeRect(r.boardImage, 0, 0, float32(r.cols*r.tileSize), float32(r.rows*r.tileSize), 1, frameAndTextColor, true)
 
-	// Settled tiles (flat)
+	// Settled tiles
 	for y := 0; y < r.rows; y++ {
 		for x := 0; x < r.cols; x++ {
-			if board.field[y][x] != nil {
-				vector.FillRect(r.boardImage, float32(x*r.tileSize), float32(y*r.tileSize), float32(r.tileSize), float32(r.tileSize), board.field[y][x], false)
+			if c := board.field.Color(x, y); c != nil {
+				r.drawTile(r.boardImage, float32(x*r.tileSize), float32(y*r.tileSize), c, board.field.Kind(x, y))
 			}
 		}
 	}
 
-	// Current piece (flat)
+	// Current piece
 	if board.currentPiece != nil {
 		for _, tile := range board.currentPiece.getTiles() {
 			px := float32(board.currentPiece.x*float64(r.tileSize) + float64(tile.x*r.tileSize))
 			py := float32(board.currentPiece.y*float64(r.tileSize) + float64(tile.y*r.tileSize))
-			vector.FillRect(r.boardImage, px, py, float32(r.tileSize), float32(r.tileSize), tile.color, false)
+			r.drawTile(r.boardImage, px, py, tile.color, int(board.currentPiece.piece.kind))
 		}
 	}
 
+	r.renderAnimations(r.boardImage)
+
+	var dx, dy float64
+	if r.animator != nil {
+		dx, dy = r.animator.Effects.Offset()
+	}
+
 	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(r.boardX, r.boardY)
+	op.GeoM.Translate(r.boardX+dx, r.boardY+dy)
 	screen.DrawImage(r.boardImage, op)
+	r.renderParticles(screen, dx, dy)
+
+	r.renderGarbageMeter(board, screen)
+}
+
+// drawTile draws a tile of the board with the skin, theme and glyphs of
+// the settings.
+func (r *Renderer) drawTile(dst *ebiten.Image, x, y float32, c color.Color, kind int) {
+	c = tileColor(c, kind)
+	skinNamed(r.settings.Skin).DrawTile(dst, x, y, float32(r.tileSize), c, kind)
+	if r.settings.Glyphs {
+		drawGlyph(dst, x, y, float32(r.tileSize), c, kind)
+	}
+}
+
+// Watch animates the events of the board from now on.
+func (r *Renderer) Watch(b *Board) {
+	if r.animator == nil || r.animator.settings != r.settings {
+		r.animator = NewAnimator(r.settings, r.rows)
+	}
+	r.animator.Watch(b)
+}
+
+// UpdateAnimations moves the animations on by a tick.
+func (r *Renderer) UpdateAnimations() {
+	if r.animator != nil {
+		r.animator.Update()
+	}
+}
+
+// Animating reports whether an animation of the kind is playing.
+func (r *Renderer) Animating(kind BoardEventKind) bool {
+	return r.animator != nil && r.animator.Running(kind)
+}
+
+// SkipAnimations ends the running animations.
+func (r *Renderer) SkipAnimations() {
+	if r.animator != nil {
+		r.animator.Skip()
+	}
+}
+
+// renderAnimations draws the running animations onto the board image. The
+// level-up banner is drawn by renderOverlays, on top of everything else.
+func (r *Renderer) renderAnimations(img *ebiten.Image) {
+	if r.animator == nil {
+		return
+	}
+
+	size := float32(r.tileSize)
+	width := float32(r.cols) * size
+
+	for _, anim := range r.animator.Active {
+		e := anim.Event
+		fade := 1 - anim.Progress()
+
+		switch e.Kind {
+		case EventLock:
+			for _, c := range e.Cells {
+				vector.FillRect(img, float32(c.X)*size, float32(c.Y)*size, size, size, withAlpha(color.White, 0.6*fade), false)
+			}
+
+		case EventClear:
+			for _, y := range e.Rows {
+				if r.settings.ClearEffect == ClearWipe {
+					w := width * float32(fade)
+					vector.FillRect(img, (width-w)/2, float32(y)*size, w, size, color.White, false)
+				} else if anim.Age/3%2 == 0 {
+					vector.FillRect(img, 0, float32(y)*size, width, size, withAlpha(color.White, fade), false)
+				}
+			}
+
+		case EventHardDrop:
+			for _, c := range e.Cells {
+				top := float32(c.Y-e.Distance) * size
+				vector.FillRect(img, float32(c.X)*size+size/4, top, size/2, float32(c.Y)*size-top, withAlpha(tileColor(e.Color, e.Piece), 0.4*fade), false)
+			}
+
+		case EventTopOut:
+			filled := int(anim.Progress() * float64(r.rows))
+			vector.FillRect(img, 0, float32(r.rows-filled)*size, width, float32(filled)*size, withAlpha(tileColor(colorGarbage, garbageKind), 0.8), false)
+		}
+	}
+}
+
+// renderParticles draws the particles over the board, fading them out as
+// they get old. They can fly off the board.
+func (r *Renderer) renderParticles(screen *ebiten.Image, dx, dy float64) {
+	if r.animator == nil {
+		return
+	}
+
+	size := float64(r.tileSize)
+	for _, p := range r.animator.Effects.Particles {
+		x := float32(r.boardX + dx + p.X*size)
+		y := float32(r.boardY + dy + p.Y*size)
+		alpha := 1 - float64(p.Age)/float64(p.Life)
+		vector.FillRect(screen, x, y, 2, 2, withAlpha(tileColor(p.Color, p.Kind), alpha), false)
+	}
+}
+
+func (r *Renderer) renderLevelBanner(screen *ebiten.Image) {
+	if r.animator == nil {
+		return
+	}
+
+	for _, anim := range r.animator.Active {
+		if anim.Event.Kind != EventLevelUp {
+			continue
+		}
+
+		// The banner rises a little and fades out in its last third
+		p := anim.Progress()
+		alpha := min(3*(1-p), 1)
+		x := r.boardX + float64(r.cols*r.tileSize)/2 - 30
+		y := r.boardY + float64(r.rows*r.tileSize)/3 - 20*p
+
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(x, y)
+		op.ColorScale.ScaleWithColor(frameAndTextColor)
+		op.ColorScale.ScaleAlpha(float32(alpha))
+		text.Draw(screen, fmt.Sprintf("LEVEL %d", anim.Event.Level), face(16), op)
+	}
+}
+
+// DrawHint outlines where the coach suggests putting the current piece.
+func (r *Renderer) DrawHint(screen *ebiten.Image, hint *FallingPiece) {
+	for _, tile := range hint.getTiles() {
+		px := float32(r.boardX + (hint.x+float64(tile.x))*float64(r.tileSize))
+		py := float32(r.boardY + (hint.y+float64(tile.y))*float64(r.tileSize))
+		vector.StrokeRect(screen, px+1, py+1, float32(r.tileSize-2), float32(r.tileSize-2), 1, hintColor, false)
+	}
+}
+
+// renderGarbageMeter draws the pending garbage as a bar left of the board.
+func (r *Renderer) renderGarbageMeter(board *Board, screen *ebiten.Image) {
+	pending := min(board.PendingGarbage(), r.rows)
+	if pending == 0 {
+		return
+	}
+
+	height := float32(pending * r.tileSize)
+	bottom := float32(r.boardY) + float32(r.rows*r.tileSize)
+	vector.FillRect(screen, float32(r.boardX)-4, bottom-height, 3, height, colorZ, false)
 }
 
 func (r *Renderer) renderNextPiece(b *Board, screen *ebiten.Image) {
@@ -144,7 +404,7 @@ func (r *Renderer) renderNextPiece(b *Board, screen *ebiten.Image) {
 			// Center the piece in the 4x4 box
 			px := float32((1 + tile.x) * r.tileSize)
 			py := float32((1 + tile.y) * r.tileSize)
-			vector.FillRect(r.nextPieceImage, px, py, float32(r.tileSize), float32(r.tileSize), tile.color, false)
+			r.drawTile(r.nextPieceImage, px, py, tile.color, int(b.pieceQueue[0].piece.kind))
 		}
 	}
 
@@ -158,64 +418,308 @@ func (r *Renderer) renderScore(b *Board, screen *ebiten.Image) {
 	scoreTitleOp := &text.DrawOptions{}
 	scoreTitleOp.GeoM.Translate(r.scoreX, r.scoreY)
 	scoreTitleOp.ColorScale.ScaleWithColor(frameAndTextColor)
-	text.Draw(screen, "SCORE", &text.GoTextFace{Source: mplusFaceSource, Size: 12}, scoreTitleOp)
+	text.Draw(screen, "SCORE", face(12), scoreTitleOp)
 
 	scoreValueOp := &text.DrawOptions{}
 	scoreValueOp.GeoM.Translate(r.scoreX, r.scoreY+15)
 	scoreValueOp.ColorScale.ScaleWithColor(frameAndTextColor)
 	scoreStr := fmt.Sprintf("%d", b.Score)
-	text.Draw(screen, scoreStr, &text.GoTextFace{Source: mplusFaceSource, Size: 12}, scoreValueOp)
+	text.Draw(screen, scoreStr, face(12), scoreValueOp)
 
 	// --- Level ---
 	levelTitleOp := &text.DrawOptions{}
 	levelTitleOp.GeoM.Translate(r.scoreX, r.scoreY+45)
 	levelTitleOp.ColorScale.ScaleWithColor(frameAndTextColor)
-	text.Draw(screen, "LEVEL", &text.GoTextFace{Source: mplusFaceSource, Size: 12}, levelTitleOp)
+	text.Draw(screen, "LEVEL", face(12), levelTitleOp)
 
 	levelValueOp := &text.DrawOptions{}
 	levelValueOp.GeoM.Translate(r.scoreX, r.scoreY+60)
 	levelValueOp.ColorScale.ScaleWithColor(frameAndTextColor)
 	levelStr := fmt.Sprintf("%d", b.Level)
-	text.Draw(screen, levelStr, &text.GoTextFace{Source: mplusFaceSource, Size: 12}, levelValueOp)
+	text.Draw(screen, levelStr, face(12), levelValueOp)
 }
 
-func (r *Renderer) renderStartGame(screen *ebiten.Image) {
-	textString := "Press [Enter] to Start"
-	op := &text.DrawOptions{}
-	op.GeoM.Translate(float64(screenW)/2-100, float64(screenH)/2)
-	op.ColorScale.ScaleWithColor(frameAndTextColor)
-	text.Draw(screen, textString, &text.GoTextFace{
-		Source: mplusFaceSource,
-		Size:   20,
-	}, op)
+// renderHUD draws the mode specific items below the score and level.
+func (r *Renderer) renderHUD(hud []HUDItem, screen *ebiten.Image) {
+	for i, item := range hud {
+		y := r.scoreY + 90 + float64(i)*30
+		r.drawText(screen, item.Label, r.scoreX, y, 12)
+		r.drawText(screen, item.Value, r.scoreX, y+15, 12)
+	}
 }
 
-func (r *Renderer) renderPauseOverlay(screen *ebiten.Image) {
-	textString := "Paused"
+// renderStats draws the statistics with a histogram of the pieces below.
+func (r *Renderer) renderStats(screen *ebiten.Image, stats *Stats, ticks int, x, y float64) {
+	items := stats.Items(ticks)
+	for i, item := range items {
+		r.drawText(screen, item.Label, x, y+float64(i)*12, 10)
+		r.drawText(screen, item.Value, x+45, y+float64(i)*12, 10)
+	}
+
+	r.renderHistogram(screen, stats, x, y+float64(len(items))*12+6)
+}
+
+// renderHistogram draws a bar for each kind of piece, followed by its count
+// and its longest drought.
+func (r *Renderer) renderHistogram(screen *ebiten.Image, stats *Stats, x, y float64) {
+	most := max(slices.Max(stats.Histogram[:]), 1)
+	for kind, count := range stats.Histogram {
+		row := y + float64(kind)*12
+		r.drawText(screen, PieceKind(kind).String(), x, row, 10)
+		width := float32(30 * count / most)
+		vector.FillRect(screen, float32(x)+10, float32(row)+3, width, 7, tileColor(pieceColors[kind], kind), false)
+		r.drawText(screen, fmt.Sprintf("%d d%d", count, stats.LongestDrought(PieceKind(kind))), x+44, row, 10)
+	}
+}
+
+// DrawResultsStats shows the statistics of a finished game.
+func (r *Renderer) DrawResultsStats(screen *ebiten.Image, title string, stats *Stats, ticks int) {
+	r.dimScreen(screen)
+
+	r.drawPageText(screen, title, 40, 15, 14)
+	r.drawPageText(screen, "STATISTICS", 40, 35, 22)
+	for i, item := range stats.Items(ticks) {
+		y := 75 + float64(i)*14
+		r.drawPageText(screen, item.Label, 60, y, 12)
+		r.drawPageText(screen, item.Value, 140, y, 12)
+	}
+	r.renderHistogram(screen, stats, r.pageX+200, r.pageY+75)
+
+	r.drawPageText(screen, "[Enter] Play again   [Esc] Back", 40, float64(screenH)-25, 10)
+}
+
+func (r *Renderer) renderCountdown(b *Board, screen *ebiten.Image) {
+	seconds := (b.countdown + ticksPerSecond - 1) / ticksPerSecond
+	x := r.boardX + float64(r.cols*r.tileSize)/2 - 8
+	y := r.boardY + float64(r.rows*r.tileSize)/2 - 20
+	r.drawText(screen, fmt.Sprintf("%d", seconds), x, y, 32)
+}
+
+func (r *Renderer) renderGameOverOverlay(screen *ebiten.Image, textString string) {
+	if r.compact {
+		x := r.boardX + float64(r.cols*r.tileSize)/2 - 35
+		y := r.boardY + float64(r.rows*r.tileSize)/2 - 10
+		r.drawText(screen, textString, x, y, 14)
+		return
+	}
+
 	op := &text.DrawOptions{}
-	op.GeoM.Translate(float64(screenW)/2-40, float64(screenH)/2)
+	op.GeoM.Translate(r.pageX+float64(screenW)/2-60, r.pageY+float64(screenH)/2-30)
 	op.ColorScale.ScaleWithColor(frameAndTextColor)
-	text.Draw(screen, textString, &text.GoTextFace{
-		Source: mplusFaceSource,
-		Size:   24,
-	}, op)
+	text.Draw(screen, textString, face(24), op)
+
+	op.GeoM.Translate(0, 30)
+	text.Draw(screen, "[Enter] Play again   [Esc] Menu", face(12), op)
 }
 
-func (r *Renderer) renderGameOverOverlay(screen *ebiten.Image) {
-	textString := "GAME OVER"
+// DrawMenu draws the menu title and its items, marking the selected one.
+func (r *Renderer) DrawMenu(screen *ebiten.Image, m *Menu) {
+	r.drawPageText(screen, m.Title, 40, 20, 20)
+
+	for i, item := range m.Items {
+		y := 60 + float64(i)*15
+		x := 60.0
+		if !item.selectable() {
+			x = 45
+		}
+
+		if i == m.selected {
+			r.drawPageText(screen, ">", x-12, y, 12)
+		}
+		r.drawPageText(screen, item.Label, x, y, 12)
+
+		if item.Value != nil {
+			r.drawPageText(screen, item.Value(), 200, y, 12)
+		}
+	}
+}
+
+// DrawTextPage draws a title and a page of at most textPageLines lines, e.g.
+// the controls help. A tab splits a line into two columns.
+func (r *Renderer) DrawTextPage(screen *ebiten.Image, title string, lines []string, page, pages int) {
+	r.drawPageText(screen, title, 40, 20, 20)
+
+	for i, line := range lines {
+		y := 60 + float64(i)*15
+		left, right, found := strings.Cut(line, "\t")
+		r.drawPageText(screen, left, 30, y, 11)
+		if found {
+			r.drawPageText(screen, right, 130, y, 11)
+		}
+	}
+
+	footer := "[Esc] Back"
+	if pages > 1 {
+		footer = fmt.Sprintf("[Left/Right] Page %d/%d   [Esc] Back", page+1, pages)
+	}
+	r.drawPageText(screen, footer, 30, float64(screenH)-25, 10)
+}
+
+// DrawResults shows the results of a finished game.
+func (r *Renderer) DrawResults(screen *ebiten.Image, title, banner string, results []HUDItem, newBest bool) {
+	r.dimScreen(screen)
+
+	r.drawPageText(screen, title, 40, 15, 14)
+	r.drawPageText(screen, banner, 40, 35, 22)
+
+	for i, item := range results {
+		y := 75 + float64(i)*18
+		r.drawPageText(screen, item.Label, 60, y, 12)
+		r.drawPageText(screen, item.Value, 160, y, 12)
+	}
+
+	if newBest {
+		r.drawPageText(screen, "NEW PERSONAL BEST!", 60, 75+float64(len(results))*18+10, 14)
+	}
+
+	r.drawPageText(screen, "[Enter] Play again   [Esc] Back", 40, float64(screenH)-25, 10)
+}
+
+// DrawFinesse shows the finesse faults of each kind of piece next to the
+// results.
+func (r *Renderer) DrawFinesse(screen *ebiten.Image, stats *FinesseStats) {
+	x := float64(screenW) - 100
+	r.drawPageText(screen, "FINESSE", x, 75, 12)
+
+	y := 93.
+	for kind, p := range stats.Pieces {
+		if p.Placed == 0 {
+			continue
+		}
+		r.drawPageText(screen, fmt.Sprintf("%s %d/%d +%d", PieceKind(kind), p.Faults, p.Placed, p.Wasted), x, y, 10)
+		y += 14
+	}
+
+	r.drawPageText(screen, fmt.Sprintf("TAP %d", stats.TapFaults), x, y+4, 10)
+}
+
+// DrawVersusResult announces the winner of a versus round or match.
+func (r *Renderer) DrawVersusResult(screen *ebiten.Image, winner int, matchOver bool) {
+	title := "DRAW"
+	if winner >= 0 {
+		title = fmt.Sprintf("PLAYER %d WINS", winner+1)
+	}
+
+	hint := "[Enter] Next round   [Esc] Menu"
+	if matchOver {
+		title += " THE MATCH"
+		hint = "[Enter] Rematch   [Esc] Menu"
+	}
+
+	r.drawPageText(screen, title, 40, float64(screenH)/2-30, 20)
+	r.drawPageText(screen, hint, 40, float64(screenH)/2+10, 12)
+}
+
+// DrawPersonalBest shows the personal best of a mode ranked by time.
+func (r *Renderer) DrawPersonalBest(screen *ebiten.Image, title string, pb PersonalBest, ok bool) {
+	r.dimScreen(screen)
+
+	r.drawPageText(screen, "PERSONAL BEST - "+title, 20, 10, 14)
+
+	if !ok {
+		r.drawPageText(screen, "No finished runs yet", 60, 55, 12)
+		return
+	}
+
+	pps := 0.0
+	if pb.Ticks > 0 {
+		pps = float64(pb.Pieces) * ticksPerSecond / float64(pb.Ticks)
+	}
+
+	items := []HUDItem{
+		{Label: "TIME", Value: formatTicks(pb.Ticks)},
+		{Label: "PIECES", Value: fmt.Sprintf("%d", pb.Pieces)},
+		{Label: "PPS", Value: fmt.Sprintf("%.2f", pps)},
+		{Label: "FINESSE", Value: fmt.Sprintf("%d", pb.Faults)},
+		{Label: "DATE", Value: pb.Date.Format("2006-01-02")},
+	}
+	for i, item := range items {
+		y := 55 + float64(i)*18
+		r.drawPageText(screen, item.Label, 60, y, 12)
+		r.drawPageText(screen, item.Value, 160, y, 12)
+	}
+}
+
+// DrawNameEntry shows the three letter name input over the game over screen.
+func (r *Renderer) DrawNameEntry(screen *ebiten.Image, e *NameEntry, score int) {
+	r.dimScreen(screen)
+
+	r.drawPageText(screen, "NEW HIGH SCORE", float64(screenW)/2-70, 40, 16)
+	r.drawPageText(screen, fmt.Sprintf("%d", score), float64(screenW)/2-70, 65, 12)
+
+	letterSize := 24.0
+	startX := float64(screenW)/2 - letterSize*nameEntryLength/2
+	for i, letter := range e.letters {
+		x := startX + float64(i)*letterSize
+		r.drawPageText(screen, string(nameEntryChars[letter]), x+5, 100, letterSize)
+
+		if i == e.cursor {
+			vector.FillRect(screen, float32(r.pageX+x+2), float32(r.pageY+100+letterSize+4), float32(letterSize-4), 2, frameAndTextColor, false)
+		}
+	}
+
+	r.drawPageText(screen, "[Up/Down] Letter  [Left/Right] Move  [Enter] OK", 20, 180, 10)
+}
+
+// DrawHighScores shows the top-10 table of a game mode.
+func (r *Renderer) DrawHighScores(screen *ebiten.Image, title string, table []HighScore) {
+	r.dimScreen(screen)
+
+	r.drawPageText(screen, "HIGH SCORES - "+title, 20, 10, 14)
+
+	columns := []float64{15, 35, 70, 130, 165, 200, 245}
+	headers := []string{"#", "NAME", "SCORE", "LINES", "LV", "TIME", "DATE"}
+	for i, header := range headers {
+		r.drawPageText(screen, header, columns[i], 35, 10)
+	}
+
+	if len(table) == 0 {
+		r.drawPageText(screen, "No scores yet", columns[1], 55, 10)
+	}
+
+	for i, entry := range table {
+		y := 55 + float64(i)*15
+		values := []string{
+			fmt.Sprintf("%d", i+1),
+			entry.Name,
+			fmt.Sprintf("%d", entry.Score),
+			fmt.Sprintf("%d", entry.Lines),
+			fmt.Sprintf("%d", entry.Level),
+			formatDuration(entry.Duration),
+			entry.Date.Format("2006-01-02"),
+		}
+		for c, value := range values {
+			r.drawPageText(screen, value, columns[c], y, 10)
+		}
+	}
+}
+
+func (r *Renderer) dimScreen(screen *ebiten.Image) {
+	dim := bgColor
+	dim.A = 0xe0
+	b := screen.Bounds()
+	vector.FillRect(screen, float32(b.Min.X), float32(b.Min.Y), float32(b.Dx()), float32(b.Dy()), dim, false)
+}
+
+func (r *Renderer) drawText(screen *ebiten.Image, s string, x, y, size float64) {
 	op := &text.DrawOptions{}
-	op.GeoM.Translate(float64(screenW)/2-60, float64(screenH)/2-30)
+	op.GeoM.Translate(x, y)
 	op.ColorScale.ScaleWithColor(frameAndTextColor)
-	text.Draw(screen, textString, &text.GoTextFace{
-		Source: mplusFaceSource,
-		Size:   24,
-	}, op)
+	text.Draw(screen, s, face(size), op)
+}
 
-	op.GeoM.Translate(0, 30)
-	text.Draw(screen, "Press [Enter] to start again", &text.GoTextFace{
-		Source: mplusFaceSource,
-		Size:   12,
-	}, op)
+// drawPageText draws text at a position on the page, which is centred on
+// screens bigger than screenW by screenH.
+func (r *Renderer) drawPageText(screen *ebiten.Image, s string, x, y, size float64) {
+	r.drawText(screen, s, r.pageX+x, r.pageY+y, size)
+}
+
+// withAlpha makes a colour see-through, keeping it premultiplied.
+func withAlpha(c color.Color, alpha float64) color.Color {
+	r, g, b, a := c.RGBA()
+	scale := func(v uint32) uint16 { return uint16(float64(v) * alpha) }
+
+	return color.RGBA64{scale(r), scale(g), scale(b), scale(a)}
 }
 
 // adjustColor is a helper to create a lighter or darker version of a color.
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Scene is one screen of the game. Game keeps them on a stack: the top scene
+// gets the input, and all of them are drawn bottom to top so overlays can
+// show the scene underneath.
+type Scene interface {
+	Update(g *Game) error
+	Draw(g *Game, screen *ebiten.Image)
+}
+
+// menuScene shows a menu, closing itself when the player goes back.
+type menuScene struct {
+	menu *Menu
+	root bool
+	// onClose is called when the scene is closed with back.
+	onClose func()
+	// onIdle is called when nothing has been pressed for attractDelayTicks.
+	onIdle    func()
+	idleTicks int
+}
+
+func (s *menuScene) Update(g *Game) error {
+	action := g.inputHandler.MenuAction()
+
+	if s.onIdle != nil {
+		s.idleTicks++
+		if action != menuNone {
+			s.idleTicks = 0
+		}
+		if s.idleTicks >= attractDelayTicks {
+			s.idleTicks = 0
+			s.onIdle()
+			return nil
+		}
+	}
+
+	if s.menu.Handle(action) && !s.root {
+		if s.onClose != nil {
+			s.onClose()
+		}
+		g.popScene()
+	}
+
+	return nil
+}
+
+func (s *menuScene) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(bgColor)
+	g.renderer.DrawMenu(screen, s.menu)
+}
+
+func newTitleScene(g *Game) Scene {
+	return &menuScene{
+		root: true,
+		menu: NewMenu("MLETRIS",
+			MenuItem{Label: "Play", Select: func() { g.pushScene(newModeSelectScene(g)) }},
+			MenuItem{Label: "Settings", Select: func() { g.pushScene(newSettingsScene(g)) }},
+			MenuItem{Label: "Controls", Select: func() { g.pushScene(newControlsScene()) }},
+			MenuItem{Label: "High Scores", Select: func() { g.pushScene(newHighScoresScene(MarathonMode{})) }},
+			MenuItem{Label: "Credits", Select: func() { g.pushScene(newCreditsScene()) }},
+			MenuItem{Label: "Quit", Select: func() { g.quit = true }},
+		),
+		onIdle: func() { g.pushScene(newAttractScene(NewBotInput(g.settings, g.botWeights))) },
+	}
+}
+
+func newModeSelectScene(g *Game) Scene {
+	goal := len(marathonGoals) - 1
+	marathon := MarathonMode{Goal: marathonGoals[goal]}
+	sprint := SprintMode{Lines: 40}
+	ultra := UltraMode{Seconds: 120}
+	dig := DigMode{Rows: 10}
+	bestOf := 3
+
+	return &menuScene{
+		menu: NewMenu("SELECT MODE",
+			MenuItem{
+				Label: "Marathon",
+				Value: func() string { return marathon.Goal.String() },
+				Adjust: func(delta int) {
+					goal = (goal + delta + len(marathonGoals)) % len(marathonGoals)
+					marathon.Goal = marathonGoals[goal]
+					marathon.StartLevel = min(marathon.StartLevel, marathon.Goal.MaxStartLevel())
+				},
+				Select: func() { g.startGame(marathon) },
+			},
+			MenuItem{
+				Label: "  Start level",
+				Value: func() string { return fmt.Sprintf("%d", marathon.StartLevel) },
+				Adjust: func(delta int) {
+					levels := marathon.Goal.MaxStartLevel() + 1
+					marathon.StartLevel = (marathon.StartLevel + delta + levels) % levels
+				},
+			},
+			MenuItem{
+				Label:  "Sprint",
+				Value:  func() string { return fmt.Sprintf("%d lines", sprint.Lines) },
+				Adjust: func(delta int) { sprint.Lines = cycle(sprintLineGoals, sprint.Lines, delta) },
+				Select: func() { g.startGame(sprint) },
+			},
+			MenuItem{
+				Label:  "Ultra",
+				Value:  func() string { return formatDuration(time.Duration(ultra.Seconds) * time.Second) },
+				Adjust: func(delta int) { ultra.Seconds = cycle(ultraTimeLimits, ultra.Seconds, delta) },
+				Select: func() { g.startGame(ultra) },
+			},
+			MenuItem{
+				Label: "  Ultra levels",
+				Value: func() string {
+					if ultra.LevelCurve {
+						return "Curve"
+					}
+					return "Frozen"
+				},
+				Adjust: func(int) { ultra.LevelCurve = !ultra.LevelCurve },
+			},
+			MenuItem{
+				Label:  "Dig",
+				Value:  func() string { return fmt.Sprintf("%d rows", dig.Rows) },
+				Adjust: func(delta int) { dig.Rows = cycle(digRowCounts, dig.Rows, delta) },
+				Select: func() { g.startGame(dig) },
+			},
+			MenuItem{
+				Label: "  Dig endless",
+				Value: func() string {
+					if dig.Endless {
+						return "On"
+					}
+					return "Off"
+				},
+				Adjust: func(int) { dig.Endless = !dig.Endless },
+			},
+			MenuItem{
+				Label:  "Versus",
+				Value:  func() string { return fmt.Sprintf("Best of %d", bestOf) },
+				Adjust: func(delta int) { bestOf = cycle(versusBestOf, bestOf, delta) },
+				Select: func() { g.startVersus(bestOf) },
+			},
+		),
+	}
+}
+
+func newSettingsScene(g *Game) Scene {
+	s := g.settings
+
+	return &menuScene{
+		menu: NewMenu("SETTINGS",
+			MenuItem{Label: "- Handling -"},
+			MenuItem{Label: "DAS", Value: func() string { return fmt.Sprintf("%d f", s.DAS) }, Adjust: s.AdjustDAS},
+			MenuItem{Label: "ARR", Value: func() string { return fmt.Sprintf("%d f", s.ARR) }, Adjust: s.AdjustARR},
+			MenuItem{Label: "- Visuals -"},
+			MenuItem{Label: "Display", Select: func() { g.pushScene(newDisplaySettingsScene(g)) }},
+			MenuItem{Label: "Animations", Select: func() { g.pushScene(newAnimationSettingsScene(g)) }},
+			MenuItem{Label: "Reduce motion", Value: onOff(&s.ReduceMotion), Adjust: func(int) { s.ReduceMotion = !s.ReduceMotion }},
+			MenuItem{Label: "- Bot -"},
+			MenuItem{Label: "Demo speed", Value: func() string { return fmt.Sprintf("%d", s.BotSpeed) }, Adjust: s.AdjustBotSpeed},
+			MenuItem{Label: "Coach", Value: func() string { return s.Coach.String() }, Adjust: s.AdjustCoach},
+		),
+		onClose: func() {
+			if err := s.Save(); err != nil {
+				log.Printf("could not save settings: %v", err)
+			}
+		},
+	}
+}
+
+// newDisplaySettingsScene changes how the board and pieces look. It is
+// saved with the other settings.
+func newDisplaySettingsScene(g *Game) Scene {
+	s := g.settings
+	paletteName := func() string {
+		if p := paletteNamed(s.Palette); p != nil {
+			return p.Name
+		}
+		return paletteOff
+	}
+
+	return &menuScene{
+		menu: NewMenu("DISPLAY",
+			MenuItem{Label: "Grid", Value: onOff(&s.ShowGrid), Adjust: func(int) { s.ShowGrid = !s.ShowGrid }},
+			MenuItem{Label: "Theme", Value: func() string { return g.themes.Named(s.Theme).Name }, Adjust: func(delta int) { s.Theme = cycle(g.themes.Names(), g.themes.Named(s.Theme).Name, delta) }},
+			MenuItem{Label: "Skin", Value: func() string { return skinNamed(s.Skin).Name() }, Adjust: func(delta int) { s.Skin = cycle(skinNames(), skinNamed(s.Skin).Name(), delta) }},
+			MenuItem{Label: "Colour blind", Value: paletteName, Adjust: func(delta int) { s.Palette = cycle(paletteNames(), paletteName(), delta) }},
+			MenuItem{Label: "Glyphs", Value: onOff(&s.Glyphs), Adjust: func(int) { s.Glyphs = !s.Glyphs }},
+			MenuItem{Label: "Pixel perfect", Value: onOff(&s.PixelPerfect), Adjust: func(int) { s.PixelPerfect = !s.PixelPerfect }},
+			MenuItem{Label: "Fullscreen", Value: onOff(&s.Fullscreen), Adjust: func(int) { g.toggleFullscreen() }},
+		),
+	}
+}
+
+// newAnimationSettingsScene turns each animation on or off. It is saved
+// with the other settings.
+func newAnimationSettingsScene(g *Game) Scene {
+	s := g.settings
+	toggle := func(v *bool) func(int) {
+		return func(int) { *v = !*v }
+	}
+
+	return &menuScene{
+		menu: NewMenu("ANIMATIONS",
+			MenuItem{Label: "Line clear", Value: func() string { return s.ClearEffect.String() }, Adjust: s.AdjustClearEffect},
+			MenuItem{Label: "Lock flash", Value: onOff(&s.LockFlash), Adjust: toggle(&s.LockFlash)},
+			MenuItem{Label: "Level banner", Value: onOff(&s.LevelBanner), Adjust: toggle(&s.LevelBanner)},
+			MenuItem{Label: "Drop trail", Value: onOff(&s.DropTrail), Adjust: toggle(&s.DropTrail)},
+			MenuItem{Label: "Top out", Value: onOff(&s.TopOutFill), Adjust: toggle(&s.TopOutFill)},
+		),
+	}
+}
+
+// onOff shows a boolean setting as a menu value.
+func onOff(v *bool) func() string {
+	return func() string {
+		if *v {
+			return "On"
+		}
+		return "Off"
+	}
+}
+
+// textScene shows text until the player goes back. Text longer than a
+// page is split into pages, turned with left and right.
+type textScene struct {
+	title string
+	pages [][]string
+	page  int
+}
+
+func newTextScene(title string, lines []string) *textScene {
+	return &textScene{title: title, pages: textPages(lines, textPageLines)}
+}
+
+func (s *textScene) Update(g *Game) error {
+	switch g.inputHandler.MenuAction() {
+	case menuLeft:
+		s.page = max(s.page-1, 0)
+	case menuRight:
+		s.page = min(s.page+1, len(s.pages)-1)
+	case menuBack, menuConfirm:
+		g.popScene()
+	}
+
+	return nil
+}
+
+func (s *textScene) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(bgColor)
+	g.renderer.DrawTextPage(screen, s.title, s.pages[s.page], s.page, len(s.pages))
+}
+
+func newControlsScene() Scene {
+	return newTextScene("CONTROLS", []string{
+		"Left / A\tMove left",
+		"Right / D\tMove right",
+		"Down / S\tSoft drop",
+		"Up / W\tRotate",
+		"Space\tHard drop",
+		"P / Esc\tPause",
+		"Tab\tStatistics",
+		"F11\tFullscreen",
+		"",
+		"Versus: player 1 uses WASD and Space,",
+		"player 2 the arrows and Enter",
+		"",
+		"Menus: arrows or D-pad, Enter or (A),",
+		"Esc or (B) to go back",
+	})
+}
+
+func newCreditsScene() Scene {
+	return newTextScene("CREDITS", []string{
+		"Mletris - a simple Tetris clone written in Go",
+		"",
+		"Made with Ebitengine",
+		"Font: M+ FONTS",
+	})
+}
+
+// highScoresScene shows the high score table or personal best of a mode.
+// Left and right switch between the modes.
+type highScoresScene struct {
+	modes    []Mode
+	selected int
+}
+
+func newHighScoresScene(mode Mode) Scene {
+	s := &highScoresScene{
+		modes: rankedModes(),
+	}
+
+	for i, m := range s.modes {
+		if m.ID() == mode.ID() {
+			s.selected = i
+		}
+	}
+
+	return s
+}
+
+func (s *highScoresScene) Update(g *Game) error {
+	n := len(s.modes)
+
+	switch g.inputHandler.MenuAction() {
+	case menuLeft:
+		s.selected = (s.selected - 1 + n) % n
+	case menuRight:
+		s.selected = (s.selected + 1) % n
+	case menuBack, menuConfirm:
+		g.popScene()
+	}
+
+	return nil
+}
+
+func (s *highScoresScene) Draw(g *Game, screen *ebiten.Image) {
+	mode := s.modes[s.selected]
+
+	if mode.RankedByTime() {
+		pb, ok := g.highScores.Best(mode.ID())
+		g.renderer.DrawPersonalBest(screen, mode.Title(), pb, ok)
+	} else {
+		g.renderer.DrawHighScores(screen, mode.Title(), g.highScores.Table(mode.ID()))
+	}
+
+	g.renderer.drawPageText(screen, "[Left/Right] Mode   [Esc] Back", 20, float64(screenH)-20, 10)
+}
+
+// playScene runs a single game, including its pause menu and game over screen.
+type playScene struct {
+	mode          Mode
+	board         *Board
+	coach         *Coach
+	pauseMenu     *Menu
+	scoreRecorded bool
+}
+
+func newPlayScene(g *Game, mode Mode) *playScene {
+	s := &playScene{
+		mode:  mode,
+		board: NewBoard(rows, cols),
+		coach: NewCoach(g.settings.Coach, g.botWeights),
+	}
+	mode.Setup(s.board)
+	g.inputHandler.Reset()
+	g.renderer.Watch(s.board)
+
+	s.pauseMenu = NewMenu("PAUSED",
+		MenuItem{Label: "Resume", Select: s.board.TogglePause},
+		MenuItem{Label: "Restart", Select: func() { g.startGame(s.mode) }},
+		MenuItem{Label: "Quit", Select: g.quitToTitle},
+	)
+
+	return s
+}
+
+func (s *playScene) Update(g *Game) error {
+	b := s.board
+
+	if g.spectators != nil {
+		defer func() { g.spectators.Publish(s.mode.Title(), b, s.mode.HUD(b)) }()
+	}
+
+	if b.gameOver || b.finished {
+		// Let the top out play before the results cover the board
+		if g.renderer.Animating(EventTopOut) {
+			g.renderer.UpdateAnimations()
+			if g.inputHandler.MenuAction() != menuNone {
+				g.renderer.SkipAnimations()
+			}
+			return nil
+		}
+
+		if !s.scoreRecorded {
+			s.scoreRecorded = true
+			s.recordResult(g)
+			return nil
+		}
+
+		switch g.inputHandler.MenuAction() {
+		case menuConfirm:
+			g.startGame(s.mode)
+		case menuBack:
+			g.quitToTitle()
+		}
+		return nil
+	}
+
+	g.inputHandler.Update(b)
+
+	if g.inputHandler.StatsPressed() {
+		g.showStats = !g.showStats
+	}
+
+	if b.paused {
+		// Start is both confirm and pause on a gamepad, so a key the menu
+		// acted on doesn't pause as well
+		action := g.inputHandler.MenuAction()
+		if s.pauseMenu.Handle(action) || (action == menuNone && g.inputHandler.PausePressed()) {
+			b.TogglePause()
+		}
+		return nil
+	}
+
+	if g.inputHandler.PausePressed() {
+		b.TogglePause()
+		s.pauseMenu.Reset()
+		return nil
+	}
+
+	playTick(b, s.mode)
+	s.coach.Update(b)
+	g.renderer.UpdateAnimations()
+
+	return nil
+}
+
+// recordResult saves the result of the game and opens the results and name
+// entry screens the mode asks for.
+func (s *playScene) recordResult(g *Game) {
+	b := s.board
+	newBest := false
+
+	if s.mode.RankedByTime() {
+		if b.finished {
+			newBest = g.highScores.RecordBest(s.mode.ID(), PersonalBest{
+				Ticks:  b.ticksPlayed,
+				Pieces: b.piecesPlaced,
+				Faults: b.finesse.Faults,
+				Date:   time.Now(),
+			})
+			if err := g.highScores.Save(); err != nil {
+				log.Printf("could not save personal best: %v", err)
+			}
+		}
+	}
+
+	if g.historyDir != "" {
+		if err := saveSession(g.historyDir, newSession(s.mode, b, g.settings, time.Now())); err != nil {
+			log.Printf("could not log the session: %v", err)
+		}
+	}
+
+	// Modes without results still get the finesse breakdown
+	g.pushScene(&resultsScene{play: s, results: s.mode.Results(b), newBest: newBest})
+
+	if !s.mode.RankedByTime() && g.highScores.Qualifies(s.mode.ID(), b.Score) {
+		g.pushScene(&nameEntryScene{play: s, entry: NewNameEntry()})
+	}
+}
+
+func (s *playScene) Draw(g *Game, screen *ebiten.Image) {
+	hud := append(s.mode.HUD(s.board), HUDItem{Label: "FINESSE", Value: fmt.Sprintf("%d", s.board.finesse.Faults)})
+	if r := s.coach.Rating; r != nil {
+		hud = append(hud, HUDItem{Label: "COACH", Value: r.Grade})
+	}
+	if g.showStats {
+		g.renderer.DrawStats(screen, s.board)
+	} else {
+		g.renderer.Draw(screen, s.board, hud)
+	}
+
+	if hint := s.coach.Hint(); hint != nil && !s.board.isStopped() {
+		g.renderer.DrawHint(screen, hint)
+	}
+
+	if s.board.paused {
+		g.renderer.dimScreen(screen)
+		g.renderer.DrawMenu(screen, s.pauseMenu)
+	}
+}
+
+// resultsScene shows how the game went once it has ended.
+type resultsScene struct {
+	play    *playScene
+	results []HUDItem
+	newBest bool
+}
+
+func (s *resultsScene) Update(g *Game) error {
+	switch g.inputHandler.MenuAction() {
+	case menuConfirm:
+		g.startGame(s.play.mode)
+	case menuBack:
+		g.popScene()
+	}
+
+	if g.inputHandler.StatsPressed() {
+		g.showStats = !g.showStats
+	}
+
+	return nil
+}
+
+func (s *resultsScene) Draw(g *Game, screen *ebiten.Image) {
+	banner := "GAME OVER"
+	if s.play.board.finished {
+		banner = s.play.mode.Banner()
+	}
+
+	b := s.play.board
+	if g.showStats {
+		g.renderer.DrawResultsStats(screen, s.play.mode.Title(), &b.stats, b.ticksPlayed)
+		return
+	}
+
+	g.renderer.DrawResults(screen, s.play.mode.Title(), banner, s.results, s.newBest)
+	g.renderer.DrawFinesse(screen, &b.finesse)
+}
+
+type nameEntryScene struct {
+	play  *playScene
+	entry *NameEntry
+}
+
+func (s *nameEntryScene) Update(g *Game) error {
+	if !g.inputHandler.UpdateNameEntry(s.entry) {
+		return nil
+	}
+
+	b := s.play.board
+	g.highScores.Add(s.play.mode.ID(), HighScore{
+		Name:     s.entry.Name(),
+		Score:    b.Score,
+		Lines:    b.totalNumberOfLinesCleared,
+		Level:    b.Level,
+		Duration: b.Duration(),
+		Date:     time.Now(),
+	})
+	if err := g.highScores.Save(); err != nil {
+		log.Printf("could not save high scores: %v", err)
+	}
+
+	g.popScene()
+	g.pushScene(newHighScoresScene(s.play.mode))
+
+	return nil
+}
+
+func (s *nameEntryScene) Draw(g *Game, screen *ebiten.Image) {
+	g.renderer.DrawNameEntry(screen, s.entry, s.play.board.Score)
+}
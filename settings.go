@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	pressDelayTicks          = 10 // 1/6 of second
+	pressRepeatIntervalTicks = 2  // 1/30 of second
+
+	minDAS = 1
+	maxDAS = 30
+	minARR = 1
+	maxARR = 10
+
+	minBotSpeed     = 1
+	maxBotSpeed     = 10
+	defaultBotSpeed = 7
+)
+
+// Settings are the user preferences changed from the settings menu.
+type Settings struct {
+	path string
+
+	// Handling
+	DAS int `json:"das"` // ticks a key has to be held before it starts repeating
+	ARR int `json:"arr"` // ticks between repeated moves
+
+	// Visuals
+	ShowGrid bool   `json:"show_grid"`
+	Skin     string `json:"skin"`  // name of the tile skin
+	Theme    string `json:"theme"` // name of the colour theme
+	// Palette replaces the piece colours for colour blind players, and
+	// Glyphs marks every tile with the shape of its piece.
+	Palette string `json:"palette"`
+	Glyphs  bool   `json:"glyphs"`
+	// PixelPerfect scales the screen by whole numbers only.
+	PixelPerfect bool `json:"pixel_perfect"`
+	Fullscreen   bool `json:"fullscreen"`
+
+	// Animations
+	ClearEffect ClearEffect `json:"clear_effect"`
+	LockFlash   bool        `json:"lock_flash"`
+	LevelBanner bool        `json:"level_banner"`
+	DropTrail   bool        `json:"drop_trail"`
+	TopOutFill  bool        `json:"top_out_fill"`
+	// ReduceMotion turns off particles and screen shake.
+	ReduceMotion bool `json:"reduce_motion"`
+
+	// Audio settings, starting with a volume, come with sound. The game has
+	// none yet, and a setting that changes nothing would only confuse.
+
+	// Bot
+	BotSpeed int `json:"bot_speed"` // how fast the demo bot presses buttons
+
+	// Coach
+	Coach CoachLevel `json:"coach"` // how often placement hints are shown
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		DAS:      pressDelayTicks,
+		ARR:      pressRepeatIntervalTicks,
+		ShowGrid: true,
+		Theme:    defaultTheme,
+		BotSpeed: defaultBotSpeed,
+
+		PixelPerfect: true,
+
+		ClearEffect: ClearFlash,
+		LockFlash:   true,
+		LevelBanner: true,
+		DropTrail:   true,
+		TopOutFill:  true,
+	}
+}
+
+// LoadSettings reads the settings from path, falling back to the defaults for
+// a missing file. An empty path keeps the settings in memory only.
+func LoadSettings(path string) (*Settings, error) {
+	s := DefaultSettings()
+	s.path = path
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return s, err
+	}
+	s.clamp()
+
+	return s, nil
+}
+
+func (s *Settings) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0o644)
+}
+
+func (s *Settings) AdjustDAS(delta int) {
+	s.DAS += delta
+	s.clamp()
+}
+
+func (s *Settings) AdjustARR(delta int) {
+	s.ARR += delta
+	s.clamp()
+}
+
+func (s *Settings) AdjustBotSpeed(delta int) {
+	s.BotSpeed += delta
+	s.clamp()
+}
+
+func (s *Settings) AdjustCoach(delta int) {
+	s.Coach = CoachLevel((int(s.Coach) + delta + len(coachLevelNames)) % len(coachLevelNames))
+}
+
+func (s *Settings) AdjustClearEffect(delta int) {
+	s.ClearEffect = ClearEffect((int(s.ClearEffect) + delta + len(clearEffectNames)) % len(clearEffectNames))
+}
+
+// BotDelay returns the ticks the bot waits between two button presses.
+func (s *Settings) BotDelay() int {
+	return (maxBotSpeed - s.BotSpeed) * 3
+}
+
+// clamp keeps values read from disk or changed in the menu in a sane range.
+func (s *Settings) clamp() {
+	s.DAS = min(max(s.DAS, minDAS), maxDAS)
+	s.ARR = min(max(s.ARR, minARR), maxARR)
+	s.BotSpeed = min(max(s.BotSpeed, minBotSpeed), maxBotSpeed)
+	s.Coach = min(max(s.Coach, CoachOff), CoachHard)
+	s.ClearEffect = min(max(s.ClearEffect, ClearOff), ClearWipe)
+}
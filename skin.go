@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Skin draws the tiles of the pieces. Tiles are drawn at any size, so
+// skins work with every tileSize.
+type Skin interface {
+	Name() string
+	// DrawTile draws a tile in colour c. Kind is the kind of the tile, as
+	// returned by Field.Kind, for skins that draw pieces differently.
+	DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int)
+}
+
+// skins are the built-in skins followed by the sprite sheets found in the
+// skins directory. The first one is the default.
+var skins = []Skin{flatSkin{}, bevelSkin{}, gradientSkin{}, outlineSkin{}}
+
+// skinNamed returns the skin with the name, or the default one.
+func skinNamed(name string) Skin {
+	for _, s := range skins {
+		if s.Name() == name {
+			return s
+		}
+	}
+
+	return skins[0]
+}
+
+func skinNames() []string {
+	names := make([]string, len(skins))
+	for i, s := range skins {
+		names[i] = s.Name()
+	}
+
+	return names
+}
+
+type flatSkin struct{}
+
+func (flatSkin) Name() string { return "Flat" }
+
+func (flatSkin) DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	vector.FillRect(dst, x, y, size, size, c, false)
+}
+
+// bevelSkin lights the top and left edges and shades the bottom and right
+// ones, like a raised button.
+type bevelSkin struct{}
+
+func (bevelSkin) Name() string { return "Bevel" }
+
+func (bevelSkin) DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	vector.FillRect(dst, x, y, size, size, c, false)
+
+	b := max(1, size/6)
+	edge := func(c color.Color, points ...float32) {
+		var p vector.Path
+		p.MoveTo(points[0], points[1])
+		for i := 2; i < len(points); i += 2 {
+			p.LineTo(points[i], points[i+1])
+		}
+		p.Close()
+
+		op := &vector.DrawPathOptions{}
+		op.ColorScale.ScaleWithColor(c)
+		vector.FillPath(dst, &p, nil, op)
+	}
+
+	light, dark := adjustColor(c, 1.5), adjustColor(c, 0.55)
+	r, bottom := x+size, y+size
+	edge(light, x, y, r, y, r-b, y+b, x+b, y+b)
+	edge(light, x, y, x+b, y+b, x+b, bottom-b, x, bottom)
+	edge(dark, x, bottom, x+b, bottom-b, r-b, bottom-b, r, bottom)
+	edge(dark, r, y, r, bottom, r-b, bottom-b, r-b, y+b)
+}
+
+// gradientSkin fades from a light top to a dark bottom.
+type gradientSkin struct{}
+
+func (gradientSkin) Name() string { return "Gradient" }
+
+func (gradientSkin) DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	const strips = 8
+	h := size / strips
+	for i := range strips {
+		factor := 1.4 - 0.8*float32(i)/(strips-1)
+		vector.FillRect(dst, x, y+float32(i)*h, size, h, adjustColor(c, factor), false)
+	}
+}
+
+// outlineSkin draws a dark tile with a bright border.
+type outlineSkin struct{}
+
+func (outlineSkin) Name() string { return "Outline" }
+
+func (outlineSkin) DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	vector.FillRect(dst, x, y, size, size, adjustColor(c, 0.3), false)
+	w := max(1, size/8)
+	vector.StrokeRect(dst, x+w/2, y+w/2, size-w, size-w, w, c, false)
+}
+
+// spriteSkin draws tiles from a sprite sheet, picking frames with
+// spriteFrame.
+type spriteSkin struct {
+	name   string
+	frames []*ebiten.Image
+}
+
+func newSpriteSkin(name string, sheet image.Image) (*spriteSkin, error) {
+	frames, err := spriteSheetFrames(name, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	img := ebiten.NewImageFromImage(sheet)
+	s := &spriteSkin{name: name}
+	for _, frame := range frames {
+		s.frames = append(s.frames, img.SubImage(frame).(*ebiten.Image))
+	}
+
+	return s, nil
+}
+
+func (s *spriteSkin) Name() string { return s.name }
+
+func (s *spriteSkin) DrawTile(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	i, tint := spriteFrame(len(s.frames), kind)
+	frame := s.frames[i]
+
+	op := &ebiten.DrawImageOptions{}
+	scale := float64(size) / float64(frame.Bounds().Dx())
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(float64(x), float64(y))
+	if tint {
+		op.ColorScale.ScaleWithColor(c)
+	}
+	dst.DrawImage(frame, op)
+}
+
+// drawGlyph marks a tile with the shape of its kind of piece, so pieces
+// can be told apart without their colours. The glyph is dark on light
+// tiles and light on dark ones. Garbage has no glyph.
+func drawGlyph(dst *ebiten.Image, x, y, size float32, c color.Color, kind int) {
+	r, g, b, _ := c.RGBA()
+	ink := color.Color(color.RGBA{0, 0, 0, 0xa0})
+	if 0.299*float32(r)+0.587*float32(g)+0.114*float32(b) < 0.5*0xffff {
+		ink = color.RGBA{0xc0, 0xc0, 0xc0, 0xc0}
+	}
+
+	w := max(1, size/8)
+	m := size / 4
+	cx, cy := x+size/2, y+size/2
+	left, top, right, bottom := x+m, y+m, x+size-m, y+size-m
+
+	switch PieceKind(kind) {
+	case PieceI:
+		vector.StrokeLine(dst, left, cy, right, cy, w, ink, true)
+	case PieceO:
+		vector.StrokeRect(dst, left, top, right-left, bottom-top, w, ink, true)
+	case PieceT:
+		vector.StrokeLine(dst, left, cy, right, cy, w, ink, true)
+		vector.StrokeLine(dst, cx, top, cx, bottom, w, ink, true)
+	case PieceS:
+		vector.StrokeLine(dst, left, bottom, right, top, w, ink, true)
+	case PieceZ:
+		vector.StrokeLine(dst, left, top, right, bottom, w, ink, true)
+	case PieceJ:
+		vector.FillCircle(dst, cx, cy, size/6, ink, true)
+	case PieceL:
+		vector.StrokeCircle(dst, cx, cy, size/4, w, ink, true)
+	}
+}
+
+// loadSpriteSkins adds a skin for every PNG sprite sheet in dir. A missing
+// directory has no skins.
+func loadSpriteSkins(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".png") {
+			continue
+		}
+
+		skin, err := loadSpriteSkin(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		skins = append(skins, skin)
+	}
+
+	return errors.Join(errs...)
+}
+
+func loadSpriteSkin(path string) (*spriteSkin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheet, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return newSpriteSkin(name, sheet)
+}
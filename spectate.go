@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+const (
+	spectateSnapshot = "snapshot"
+	spectateDelta    = "delta"
+	// spectatorBuffer is the number of messages a slow spectator may fall
+	// behind before it's dropped.
+	spectatorBuffer = 256
+)
+
+// BoardSnapshot is everything a spectator needs to draw a board. Cells hold
+// the RGBA colour of the block, 0 for an empty cell.
+type BoardSnapshot struct {
+	Title     string        `json:"title"`
+	Field     [][]uint32    `json:"field,omitempty"`
+	Piece     PieceSnapshot `json:"piece"`
+	Queue     []PieceKind   `json:"queue"`
+	Score     int           `json:"score"`
+	Level     int           `json:"level"`
+	Lines     int           `json:"lines"`
+	Pending   int           `json:"pending"`
+	Countdown int           `json:"countdown"`
+	Paused    bool          `json:"paused"`
+	GameOver  bool          `json:"gameOver"`
+	Finished  bool          `json:"finished"`
+	HUD       []HUDItem     `json:"hud"`
+}
+
+type PieceSnapshot struct {
+	Kind  PieceKind `json:"kind"`
+	State int       `json:"state"`
+	X     float64   `json:"x"`
+	Y     float64   `json:"y"`
+}
+
+// SpectateMessage is sent to spectators as a WebSocket text message. A
+// snapshot carries the whole field, a delta only the rows that changed.
+type SpectateMessage struct {
+	Type   string           `json:"type"`
+	Frame  int              `json:"frame"`
+	Board  BoardSnapshot    `json:"board"`
+	Rows   map[int][]uint32 `json:"rows,omitempty"`
+	Events []string         `json:"events,omitempty"`
+}
+
+func takeSnapshot(title string, b *Board, hud []HUDItem) *BoardSnapshot {
+	s := &BoardSnapshot{
+		Title:     title,
+		Field:     make([][]uint32, b.field.Height()),
+		Score:     b.Score,
+		Level:     b.Level,
+		Lines:     b.totalNumberOfLinesCleared,
+		Pending:   b.pendingGarbage,
+		Countdown: b.countdown,
+		Paused:    b.paused,
+		GameOver:  b.gameOver,
+		Finished:  b.finished,
+		HUD:       hud,
+	}
+
+	for y := range s.Field {
+		s.Field[y] = make([]uint32, b.field.Width())
+		for x := range s.Field[y] {
+			s.Field[y][x] = cellRGBA(b.field.Color(x, y))
+		}
+	}
+
+	if p := b.currentPiece; p != nil {
+		s.Piece = PieceSnapshot{Kind: p.piece.kind, State: p.state, X: p.x, Y: p.y}
+	}
+
+	for _, next := range b.pieceQueue {
+		s.Queue = append(s.Queue, next.piece.kind)
+	}
+
+	return s
+}
+
+// apply copies the snapshot into a board so it can be drawn by a Renderer.
+func (s *BoardSnapshot) apply(b *Board) {
+	if len(s.Field) > 0 {
+		b.field = createField(len(s.Field), len(s.Field[0]))
+		for y, row := range s.Field {
+			b.field.setRow(y, row)
+		}
+	}
+
+	b.currentPiece = s.Piece.fallingPiece(b)
+	b.pieceQueue = b.pieceQueue[:0]
+	for _, kind := range s.Queue {
+		b.pieceQueue = append(b.pieceQueue, PieceSnapshot{Kind: kind}.fallingPiece(b))
+	}
+
+	b.Score = s.Score
+	b.Level = s.Level
+	b.totalNumberOfLinesCleared = s.Lines
+	b.pendingGarbage = s.Pending
+	b.countdown = s.Countdown
+	b.paused = s.Paused
+	b.gameOver = s.GameOver
+	b.finished = s.Finished
+}
+
+func (p PieceSnapshot) fallingPiece(b *Board) *FallingPiece {
+	kind := min(max(int(p.Kind), 0), len(b.tiles)-1)
+	piece := b.tiles[kind]
+
+	return &FallingPiece{
+		piece: piece,
+		state: min(max(p.State, 0), len(piece.data)-1),
+		x:     p.X,
+		y:     p.Y,
+	}
+}
+
+func cellRGBA(c color.Color) uint32 {
+	if c == nil {
+		return 0
+	}
+
+	r, g, b, a := c.RGBA()
+	return r>>8<<24 | g>>8<<16 | b>>8<<8 | a>>8
+}
+
+func rgbaCell(v uint32) color.Color {
+	if v == 0 {
+		return nil
+	}
+
+	return color.RGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+}
+
+// setRow fills a row of the field from streamed cells. Only colours are
+// streamed, so the kinds of the blocks come from them.
+func (f *Field) setRow(y int, cells []uint32) {
+	f.ClearRow(y)
+	for x, cell := range cells[:min(len(cells), f.width)] {
+		c := rgbaCell(cell)
+		f.Set(x, y, c, tileKind(c))
+	}
+}
+
+// SpectateServer streams a running game to any number of spectators over
+// WebSocket. New spectators get a full snapshot, after that only deltas.
+type SpectateServer struct {
+	mu      sync.Mutex
+	frame   int
+	last    *BoardSnapshot
+	lastRaw []byte // the last snapshot without its field, to skip idle frames
+	clients map[*spectatorConn]bool
+}
+
+type spectatorConn struct {
+	conn *wsConn
+	out  chan []byte
+}
+
+func NewSpectateServer() *SpectateServer {
+	return &SpectateServer{clients: map[*spectatorConn]bool{}}
+}
+
+// Publish sends what has changed on the board since the last call. It is
+// called from the game loop once per frame.
+func (s *SpectateServer) Publish(title string, b *Board, hud []HUDItem) {
+	snapshot := takeSnapshot(title, b, hud)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.frame++
+	msg := SpectateMessage{Type: spectateDelta, Frame: s.frame, Board: *snapshot}
+	msg.Board.Field = nil
+
+	raw, _ := json.Marshal(msg.Board)
+	if s.last == nil {
+		// Spectators that joined before the game started need a whole
+		// board to build on
+		msg.Type = spectateSnapshot
+		msg.Board.Field = snapshot.Field
+	} else {
+		msg.Rows = changedRows(s.last.Field, snapshot.Field)
+		msg.Events = boardEvents(s.last, snapshot, b)
+	}
+
+	idle := s.last != nil && len(msg.Rows) == 0 && bytes.Equal(raw, s.lastRaw)
+	s.last = snapshot
+	s.lastRaw = raw
+	if idle {
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("spectate: %v", err)
+		return
+	}
+
+	for c := range s.clients {
+		s.send(c, data)
+	}
+}
+
+// send queues a message for a spectator, dropping spectators that can't
+// keep up rather than slowing the game down.
+func (s *SpectateServer) send(c *spectatorConn, data []byte) {
+	select {
+	case c.out <- data:
+	default:
+		delete(s.clients, c)
+		close(c.out)
+	}
+}
+
+func changedRows(before, after [][]uint32) map[int][]uint32 {
+	rows := map[int][]uint32{}
+	for y := range after {
+		if y >= len(before) || !slices.Equal(before[y], after[y]) {
+			rows[y] = after[y]
+		}
+	}
+
+	return rows
+}
+
+// boardEvents names what happened between two snapshots, for spectators to
+// announce.
+func boardEvents(before, after *BoardSnapshot, b *Board) []string {
+	var events []string
+
+	if after.Lines > before.Lines {
+		events = append(events, b.lastClear.Name())
+	}
+	if after.Level > before.Level {
+		events = append(events, fmt.Sprintf("LEVEL %d", after.Level))
+	}
+	if after.GameOver && !before.GameOver {
+		events = append(events, "GAME OVER")
+	}
+	if after.Finished && !before.Finished {
+		events = append(events, "FINISHED")
+	}
+
+	return events
+}
+
+// ServeHTTP upgrades a spectator's request to a WebSocket and streams the
+// game to it until it disconnects.
+func (s *SpectateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	c := &spectatorConn{conn: conn, out: make(chan []byte, spectatorBuffer)}
+
+	s.mu.Lock()
+	s.clients[c] = true
+	if s.last != nil {
+		data, _ := json.Marshal(SpectateMessage{Type: spectateSnapshot, Frame: s.frame, Board: *s.last})
+		s.send(c, data)
+	}
+	s.mu.Unlock()
+
+	// Spectators don't send anything, but reading notices when they leave
+	go func() {
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				s.remove(c)
+				return
+			}
+		}
+	}()
+
+	for data := range c.out {
+		if err := conn.WriteText(data); err != nil {
+			s.remove(c)
+			break
+		}
+	}
+	conn.Close()
+}
+
+func (s *SpectateServer) remove(c *spectatorConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.out)
+	}
+}
+
+// Spectators returns the number of connected spectators.
+func (s *SpectateServer) Spectators() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.clients)
+}
+
+// Spectator follows a game streamed by a SpectateServer. Its Board mirrors
+// the streamed board and can be drawn with a Renderer.
+type Spectator struct {
+	conn      *wsConn
+	incoming  chan SpectateMessage
+	done      chan struct{} // closed when the read loop stops
+	closed    chan struct{} // closed by Close
+	closeOnce sync.Once
+	err       error
+
+	Board  *Board
+	Title  string
+	HUD    []HUDItem
+	Events []string
+	// Synced is true once the first snapshot has arrived.
+	Synced bool
+	Frame  int
+	Err    error
+}
+
+func DialSpectator(url string) (*Spectator, error) {
+	conn, err := wsDial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spectator{
+		conn:     conn,
+		incoming: make(chan SpectateMessage, spectatorBuffer),
+		done:     make(chan struct{}),
+		closed:   make(chan struct{}),
+		Board:    NewBoardWithSeed(rows, cols, 0),
+	}
+	go s.readLoop()
+
+	return s, nil
+}
+
+func (s *Spectator) readLoop() {
+	defer close(s.done)
+
+	for {
+		data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		var msg SpectateMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.err = err
+			return
+		}
+
+		// Nobody reads the messages after Close
+		select {
+		case s.incoming <- msg:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// Poll applies the messages that have arrived to the board. Events only
+// holds the events of those messages.
+func (s *Spectator) Poll() {
+	s.Events = s.Events[:0]
+
+	for {
+		select {
+		case msg := <-s.incoming:
+			s.apply(msg)
+		case <-s.done:
+			for len(s.incoming) > 0 {
+				s.apply(<-s.incoming)
+			}
+			if s.Err == nil {
+				s.Err = s.err
+			}
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *Spectator) apply(msg SpectateMessage) {
+	switch msg.Type {
+	case spectateSnapshot:
+		s.Synced = true
+	case spectateDelta:
+		// Deltas are useless until the snapshot they build on has arrived
+		if !s.Synced {
+			return
+		}
+		for y, row := range msg.Rows {
+			if y >= 0 && y < s.Board.field.Height() {
+				s.Board.field.setRow(y, row)
+			}
+		}
+	default:
+		return
+	}
+
+	msg.Board.apply(s.Board)
+	s.Title = msg.Board.Title
+	s.HUD = msg.Board.HUD
+	s.Frame = msg.Frame
+	s.Events = append(s.Events, msg.Events...)
+}
+
+func (s *Spectator) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return s.conn.Close()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startSpectateServer(t *testing.T) (*SpectateServer, string) {
+	t.Helper()
+
+	server := NewSpectateServer()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	return server, "ws" + strings.TrimPrefix(ts.URL, "http") + "/spectate"
+}
+
+func dialSpectator(t *testing.T, server *SpectateServer, url string) *Spectator {
+	t.Helper()
+
+	before := server.Spectators()
+	s, err := DialSpectator(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	// Wait until the server has registered the new spectator
+	waitFor(t, func() bool { return server.Spectators() > before })
+
+	return s
+}
+
+// waitFor polls until cond is true or fails the test after a while.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// playSomePieces hard drops pieces in different columns and publishes every
+// frame.
+func playSomePieces(server *SpectateServer, b *Board, pieces int) {
+	for i := 0; i < pieces && !b.gameOver; i++ {
+		for range i % 4 {
+			b.MoveLeft()
+		}
+		b.Fall()
+		server.Publish("TEST", b, []HUDItem{{Label: "PIECES", Value: "n"}})
+	}
+}
+
+func waitForBoard(t *testing.T, s *Spectator, want *Board) {
+	t.Helper()
+
+	wantField := fieldToString(&want.field)
+	waitFor(t, func() bool {
+		s.Poll()
+		return s.Synced && s.Board.Score == want.Score && fieldToString(&s.Board.field) == wantField
+	})
+
+	if s.Board.currentPiece.piece.kind != want.currentPiece.piece.kind {
+		t.Errorf("current piece = %d, want %d", s.Board.currentPiece.piece.kind, want.currentPiece.piece.kind)
+	}
+	for i, next := range want.pieceQueue {
+		if s.Board.pieceQueue[i].piece.kind != next.piece.kind {
+			t.Errorf("queue[%d] = %d, want %d", i, s.Board.pieceQueue[i].piece.kind, next.piece.kind)
+		}
+	}
+	if s.Title != "TEST" || len(s.HUD) != 1 {
+		t.Errorf("title %q and HUD %v were not streamed", s.Title, s.HUD)
+	}
+}
+
+func TestSpectate_LateJoinerGetsSnapshotThenDeltas(t *testing.T) {
+	server, url := startSpectateServer(t)
+	b := NewBoardWithSeed(rows, cols, 42)
+
+	early := dialSpectator(t, server, url)
+	playSomePieces(server, b, 6)
+	waitForBoard(t, early, b)
+
+	late := dialSpectator(t, server, url)
+	waitFor(t, func() bool {
+		late.Poll()
+		return late.Synced
+	})
+	waitForBoard(t, late, b)
+
+	playSomePieces(server, b, 6)
+	waitForBoard(t, early, b)
+	waitForBoard(t, late, b)
+}
+
+func TestSpectate_DeltasOnlyCarryChangedRows(t *testing.T) {
+	server, _ := startSpectateServer(t)
+	b := NewBoardWithSeed(rows, cols, 42)
+
+	server.Publish("TEST", b, nil)
+	before := server.last
+	b.Fall()
+	server.Publish("TEST", b, nil)
+
+	rows := changedRows(before.Field, server.last.Field)
+	if len(rows) == 0 || len(rows) > 4 {
+		t.Errorf("a single piece changed %d rows", len(rows))
+	}
+}
+
+func TestWSUpgrade_RejectsOtherVersions(t *testing.T) {
+	_, url := startSpectateServer(t)
+
+	req, err := http.NewRequest(http.MethodGet, "http"+strings.TrimPrefix(url, "ws"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != wsVersion {
+		t.Errorf("Expected %d with version %s, got %d with %q", http.StatusUpgradeRequired, wsVersion, resp.StatusCode, resp.Header.Get("Sec-WebSocket-Version"))
+	}
+}
+
+func TestSpectator_CloseStopsReading(t *testing.T) {
+	server, url := startSpectateServer(t)
+	s := dialSpectator(t, server, url)
+	b := NewBoardWithSeed(rows, cols, 42)
+
+	// More messages than fit, and nobody polls them
+	for i := range spectatorBuffer + 10 {
+		server.Publish(fmt.Sprintf("TEST %d", i), b, nil)
+	}
+	waitFor(t, func() bool { return len(s.incoming) == spectatorBuffer })
+
+	s.Close()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the read loop is still blocked after Close")
+	}
+}
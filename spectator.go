@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// spectatorEventTicks is how long an event stays on screen.
+const spectatorEventTicks = 90
+
+// spectatorScene watches a game streamed by another player.
+type spectatorScene struct {
+	url        string
+	connected  chan spectateResult
+	spectator  *Spectator
+	err        error
+	event      string
+	eventTicks int
+}
+
+type spectateResult struct {
+	spectator *Spectator
+	err       error
+}
+
+func newSpectatorScene(url string) *spectatorScene {
+	s := &spectatorScene{
+		url:       url,
+		connected: make(chan spectateResult, 1),
+	}
+
+	go func() {
+		spectator, err := DialSpectator(url)
+		s.connected <- spectateResult{spectator, err}
+	}()
+
+	return s
+}
+
+func (s *spectatorScene) Update(g *Game) error {
+	if g.inputHandler.MenuAction() == menuBack {
+		if s.spectator != nil {
+			s.spectator.Close()
+		}
+		g.quitToTitle()
+		return nil
+	}
+
+	if s.spectator == nil {
+		select {
+		case r := <-s.connected:
+			s.spectator, s.err = r.spectator, r.err
+		default:
+		}
+		return nil
+	}
+
+	s.spectator.Poll()
+	if n := len(s.spectator.Events); n > 0 {
+		s.event = s.spectator.Events[n-1]
+		s.eventTicks = spectatorEventTicks
+	} else if s.eventTicks > 0 {
+		s.eventTicks--
+	}
+
+	return nil
+}
+
+func (s *spectatorScene) Draw(g *Game, screen *ebiten.Image) {
+	if s.spectator == nil || !s.spectator.Synced {
+		screen.Fill(bgColor)
+
+		status := "Waiting for " + s.url + "..."
+		switch {
+		case s.err != nil:
+			status = "Could not watch: " + s.err.Error()
+		case s.spectator != nil && s.spectator.Err != nil:
+			status = "Stream ended: " + s.spectator.Err.Error()
+		}
+		g.renderer.drawPageText(screen, status, 20, float64(screenH)/2-10, 12)
+		g.renderer.drawPageText(screen, "[Esc] Menu", 20, float64(screenH)/2+10, 12)
+		return
+	}
+
+	g.renderer.Draw(screen, s.spectator.Board, s.spectator.HUD)
+
+	status := "WATCHING " + s.spectator.Title
+	if s.spectator.Err != nil {
+		status = "STREAM ENDED"
+	}
+	bottom := float64(screen.Bounds().Dy())
+	g.renderer.drawText(screen, status, 10, bottom-14, 10)
+
+	if s.eventTicks > 0 {
+		g.renderer.drawText(screen, s.event, 10, bottom-28, 10)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"image"
+)
+
+// spriteSheetFrames splits a sprite sheet into its frames: a row of square
+// frames as high as the sheet.
+func spriteSheetFrames(name string, sheet image.Image) ([]image.Rectangle, error) {
+	bounds := sheet.Bounds()
+	size := bounds.Dy()
+	if size == 0 || bounds.Dx()%size != 0 {
+		return nil, fmt.Errorf("sprite sheet %s is not a row of square frames", name)
+	}
+
+	var frames []image.Rectangle
+	for x := bounds.Min.X; x < bounds.Max.X; x += size {
+		frames = append(frames, image.Rect(x, bounds.Min.Y, x+size, bounds.Max.Y))
+	}
+
+	return frames, nil
+}
+
+// spriteFrame returns the frame of a sheet of n frames to draw a tile of a
+// kind with, and whether to tint it with the tile's colour. A sheet with a
+// frame for each kind of piece, and optionally one more for garbage, has
+// its own frames for them. Everything else is the first frame, tinted.
+func spriteFrame(n, kind int) (frame int, tint bool) {
+	if kind >= 0 && kind < n && n >= len(pieceNames) {
+		return kind, false
+	}
+
+	return 0, true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSpriteSheet(t *testing.T) {
+	tests := []struct {
+		name    string
+		sheet   image.Rectangle
+		frames  int
+		wantErr bool
+		// The frames and tints for an L piece and for garbage
+		pieceFrame, garbageFrame int
+		pieceTint, garbageTint   bool
+	}{
+		{name: "single frame", sheet: image.Rect(0, 0, 16, 16), frames: 1, pieceTint: true, garbageTint: true},
+		{name: "too few frames", sheet: image.Rect(0, 0, 48, 16), frames: 3, pieceTint: true, garbageTint: true},
+		{name: "per piece", sheet: image.Rect(0, 0, 7*16, 16), frames: 7, pieceFrame: int(PieceL), garbageTint: true},
+		{name: "per piece and garbage", sheet: image.Rect(0, 0, 8*8, 8), frames: 8, pieceFrame: int(PieceL), garbageFrame: garbageKind},
+		{name: "offset sheet", sheet: image.Rect(5, 5, 5+7*4, 9), frames: 7, pieceFrame: int(PieceL), garbageTint: true},
+		{name: "not square", sheet: image.Rect(0, 0, 20, 16), wantErr: true},
+		{name: "taller than wide", sheet: image.Rect(0, 0, 8, 16), wantErr: true},
+		{name: "empty", sheet: image.Rect(0, 0, 16, 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames, err := spriteSheetFrames(tt.name, image.NewRGBA(tt.sheet))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected the sheet to be rejected, got %d frames", len(frames))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the sheet to load, got %v", err)
+			}
+
+			if len(frames) != tt.frames {
+				t.Fatalf("Expected %d frames, got %d", tt.frames, len(frames))
+			}
+			size := tt.sheet.Dy()
+			for i, f := range frames {
+				if f.Dx() != size || f.Dy() != size || f.Min.X != tt.sheet.Min.X+i*size {
+					t.Errorf("Unexpected frame %d %v", i, f)
+				}
+			}
+
+			if frame, tint := spriteFrame(len(frames), int(PieceL)); frame != tt.pieceFrame || tint != tt.pieceTint {
+				t.Errorf("Expected piece frame %d, tinted %v, got %d, %v", tt.pieceFrame, tt.pieceTint, frame, tint)
+			}
+			if frame, tint := spriteFrame(len(frames), garbageKind); frame != tt.garbageFrame || tint != tt.garbageTint {
+				t.Errorf("Expected garbage frame %d, tinted %v, got %d, %v", tt.garbageFrame, tt.garbageTint, frame, tint)
+			}
+			if frame, tint := spriteFrame(len(frames), -1); frame != 0 || !tint {
+				t.Errorf("Expected the first frame tinted for no kind, got %d, %v", frame, tint)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import "fmt"
+
+// Stats are gathered from the pieces a board locks, for the statistics page
+// and the results screen.
+type Stats struct {
+	Pieces     int
+	KeyPresses int // move and rotate presses, as counted for finesse
+	Attack     int // garbage lines the clears were worth
+
+	// Clears counts the locks by the number of lines they cleared, 1 to 4.
+	Clears    [5]int
+	Histogram [len(pieceNames)]int
+
+	// LevelTicks are the ticks played when each level up happened.
+	LevelTicks []int
+
+	drought [len(pieceNames)]int // pieces locked since each kind last locked
+	longest [len(pieceNames)]int
+}
+
+// record adds a locked piece to the statistics.
+func (s *Stats) record(kind PieceKind, keyPresses, lines, attack int) {
+	s.Pieces++
+	s.KeyPresses += keyPresses
+	s.Attack += attack
+	s.Clears[min(lines, 4)]++
+	s.Histogram[kind]++
+
+	for k := range s.drought {
+		if PieceKind(k) == kind {
+			s.drought[k] = 0
+		} else {
+			s.drought[k]++
+			s.longest[k] = max(s.longest[k], s.drought[k])
+		}
+	}
+}
+
+// PiecesPerSecond is the number of pieces locked per second over ticks.
+func (s *Stats) PiecesPerSecond(ticks int) float64 {
+	if ticks == 0 {
+		return 0
+	}
+
+	return float64(s.Pieces) * ticksPerSecond / float64(ticks)
+}
+
+// AttackPerMinute is the number of garbage lines sent per minute over ticks.
+func (s *Stats) AttackPerMinute(ticks int) float64 {
+	if ticks == 0 {
+		return 0
+	}
+
+	return float64(s.Attack) * 60 * ticksPerSecond / float64(ticks)
+}
+
+// KeysPerPiece is the average number of key presses per piece.
+func (s *Stats) KeysPerPiece() float64 {
+	if s.Pieces == 0 {
+		return 0
+	}
+
+	return float64(s.KeyPresses) / float64(s.Pieces)
+}
+
+// Lines returns the number of lines cleared.
+func (s *Stats) Lines() int {
+	lines := 0
+	for n, count := range s.Clears {
+		lines += n * count
+	}
+
+	return lines
+}
+
+// TetrisRate is the share of the cleared lines that were cleared by tetrises.
+func (s *Stats) TetrisRate() float64 {
+	lines := s.Lines()
+	if lines == 0 {
+		return 0
+	}
+
+	return float64(4*s.Clears[4]) / float64(lines)
+}
+
+// LongestDrought returns the most pieces locked in a row without the kind.
+func (s *Stats) LongestDrought(kind PieceKind) int {
+	return s.longest[kind]
+}
+
+// Items lists the statistics for a board that has been played for ticks.
+func (s *Stats) Items(ticks int) []HUDItem {
+	return []HUDItem{
+		{Label: "PIECES", Value: fmt.Sprintf("%d", s.Pieces)},
+		{Label: "PPS", Value: fmt.Sprintf("%.2f", s.PiecesPerSecond(ticks))},
+		{Label: "APM", Value: fmt.Sprintf("%.1f", s.AttackPerMinute(ticks))},
+		{Label: "KPP", Value: fmt.Sprintf("%.2f", s.KeysPerPiece())},
+		{Label: "SINGLE", Value: fmt.Sprintf("%d", s.Clears[1])},
+		{Label: "DOUBLE", Value: fmt.Sprintf("%d", s.Clears[2])},
+		{Label: "TRIPLE", Value: fmt.Sprintf("%d", s.Clears[3])},
+		{Label: "TETRIS", Value: fmt.Sprintf("%d", s.Clears[4])},
+		{Label: "TRT", Value: fmt.Sprintf("%.0f%%", s.TetrisRate()*100)},
+	}
+}
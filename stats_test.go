@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestStats_Record(t *testing.T) {
+	var s Stats
+	locks := []struct {
+		kind        PieceKind
+		keys, lines int
+	}{
+		{PieceI, 2, 0},
+		{PieceO, 1, 0},
+		{PieceO, 3, 4},
+		{PieceT, 0, 1},
+		{PieceI, 2, 4},
+	}
+	for _, l := range locks {
+		s.record(l.kind, l.keys, l.lines, lineAttack[l.lines])
+	}
+
+	if s.Pieces != 5 || s.KeysPerPiece() != 1.6 {
+		t.Errorf("Expected 5 pieces at 1.6 keys each, got %d at %.2f", s.Pieces, s.KeysPerPiece())
+	}
+
+	if s.Clears[1] != 1 || s.Clears[4] != 2 || s.Lines() != 9 {
+		t.Errorf("Unexpected clears %v", s.Clears)
+	}
+
+	if rate := s.TetrisRate(); rate < 0.88 || rate > 0.89 {
+		t.Errorf("Expected a tetris rate of 8/9, got %.2f", rate)
+	}
+
+	if s.Histogram[PieceO] != 2 || s.Histogram[PieceI] != 2 || s.Histogram[PieceL] != 0 {
+		t.Errorf("Unexpected histogram %v", s.Histogram)
+	}
+
+	// Three pieces came between the two I pieces; no L piece came at all
+	if got := s.LongestDrought(PieceI); got != 3 {
+		t.Errorf("Expected an I drought of 3, got %d", got)
+	}
+	if got := s.LongestDrought(PieceL); got != 5 {
+		t.Errorf("Expected an L drought of 5, got %d", got)
+	}
+
+	// 8 lines of attack in one minute
+	if apm := s.AttackPerMinute(60 * ticksPerSecond); apm != 8 {
+		t.Errorf("Expected 8 APM, got %.1f", apm)
+	}
+}
+
+func TestStats_RecordedOnLock(t *testing.T) {
+	b := NewBoard(rows, cols)
+	fillBoardFromString(b, bottomRows(`
+xxxxxxxxx.
+xxxxxxxxx.`))
+
+	b.currentPiece = &FallingPiece{piece: b.tiles[PieceI], x: 9., y: 1.}
+	b.countKeyPress()
+	b.Fall()
+
+	if b.stats.Pieces != 1 || b.stats.Clears[2] != 1 || b.stats.KeyPresses != 1 {
+		t.Errorf("Expected one double with one key press, got %+v", b.stats)
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+)
+
+// The Tetris Bot Protocol (TBP) lets external engines like Cold Clear play
+// the game. The bot is a child process reading and writing one JSON message
+// per line on its stdin and stdout.
+
+// tbpBoardHeight is the number of rows of a TBP board, counted from the
+// bottom. The rows above our field are sent empty.
+const tbpBoardHeight = 40
+
+// tbpPieces are the TBP names of the pieces in PieceKind order.
+var tbpPieces = []string{"I", "O", "T", "S", "Z", "J", "L"}
+
+var tbpOrientations = []string{"north", "east", "south", "west"}
+
+// tbpShapes are the cells of each piece facing north, relative to its SRS
+// centre, with y going up.
+var tbpShapes = [][4][2]int{
+	PieceI: {{-1, 0}, {0, 0}, {1, 0}, {2, 0}},
+	PieceO: {{0, 0}, {1, 0}, {0, 1}, {1, 1}},
+	PieceT: {{-1, 0}, {0, 0}, {1, 0}, {0, 1}},
+	PieceS: {{-1, 0}, {0, 0}, {0, 1}, {1, 1}},
+	PieceZ: {{-1, 1}, {0, 1}, {0, 0}, {1, 0}},
+	PieceJ: {{-1, 0}, {0, 0}, {1, 0}, {-1, 1}},
+	PieceL: {{-1, 0}, {0, 0}, {1, 0}, {1, 1}},
+}
+
+type TBPLocation struct {
+	Type        string `json:"type"`
+	Orientation string `json:"orientation"`
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+}
+
+type TBPMove struct {
+	Location TBPLocation `json:"location"`
+	Spin     string      `json:"spin"`
+}
+
+// TBPMessage holds the fields of every message a bot can send.
+type TBPMessage struct {
+	Type     string    `json:"type"`
+	Name     string    `json:"name,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Author   string    `json:"author,omitempty"`
+	Features []string  `json:"features,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Moves    []TBPMove `json:"moves,omitempty"`
+}
+
+type tbpStart struct {
+	Type       string      `json:"type"`
+	Hold       *string     `json:"hold"`
+	Queue      []string    `json:"queue"`
+	Combo      int         `json:"combo"`
+	BackToBack bool        `json:"back_to_back"`
+	Board      [][]*string `json:"board"`
+}
+
+type tbpNewPiece struct {
+	Type  string `json:"type"`
+	Piece string `json:"piece"`
+}
+
+type tbpPlay struct {
+	Type string  `json:"type"`
+	Move TBPMove `json:"move"`
+}
+
+type tbpCommand struct {
+	Type string `json:"type"`
+}
+
+// TBPBot is a running bot process that has accepted our rules.
+type TBPBot struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Scanner
+	// Info is what the bot told about itself when it started.
+	Info TBPMessage
+}
+
+// StartTBPBot runs a bot and waits until it is ready to play.
+func StartTBPBot(name string, args ...string) (*TBPBot, error) {
+	cmd := exec.Command(name, args...)
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	t := &TBPBot{cmd: cmd, in: in, out: bufio.NewScanner(out)}
+	t.out.Buffer(nil, 1<<20)
+
+	if t.Info, err = t.receive(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	if t.Info.Type != "info" {
+		t.Close()
+		return nil, fmt.Errorf("bot sent %q instead of info", t.Info.Type)
+	}
+
+	if err := t.send(tbpCommand{Type: "rules"}); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	msg, err := t.receive()
+	if err == nil && msg.Type != "ready" {
+		err = fmt.Errorf("bot did not accept the rules: %s %s", msg.Type, msg.Reason)
+	}
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *TBPBot) send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.in.Write(append(data, '\n'))
+	return err
+}
+
+func (t *TBPBot) receive() (TBPMessage, error) {
+	var msg TBPMessage
+
+	if !t.out.Scan() {
+		if err := t.out.Err(); err != nil {
+			return msg, err
+		}
+		return msg, io.EOF
+	}
+
+	err := json.Unmarshal(t.out.Bytes(), &msg)
+	return msg, err
+}
+
+// suggest asks the bot for its moves for the first piece of its queue.
+func (t *TBPBot) suggest() ([]TBPMove, error) {
+	if err := t.send(tbpCommand{Type: "suggest"}); err != nil {
+		return nil, err
+	}
+
+	for {
+		msg, err := t.receive()
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg.Type {
+		case "suggestion":
+			return msg.Moves, nil
+		case "error":
+			return nil, fmt.Errorf("bot error: %s", msg.Reason)
+		}
+	}
+}
+
+// Close asks the bot to quit and waits for it.
+func (t *TBPBot) Close() error {
+	t.send(tbpCommand{Type: "quit"})
+	t.in.Close()
+
+	return t.cmd.Wait()
+}
+
+// TBPDriver lets a TBP bot choose the placements of a BotInput. It tells the
+// bot about every new piece and resends the whole board when the field
+// changes in a way the bot can't know about, e.g. when garbage rises.
+type TBPDriver struct {
+	bot *TBPBot
+
+	board    *Board
+	told     int      // pieces the bot has been told about
+	expected []uint16 // the field once the last played move locks
+
+	thinking bool
+	result   chan tbpResult
+
+	// Played counts the bot's moves used in the game. Fallbacks counts the
+	// pieces placed by the built-in heuristic because the bot failed or
+	// suggested a placement that can't be reached.
+	Played    int
+	Fallbacks int
+	Err       error
+}
+
+type tbpResult struct {
+	moves []TBPMove
+	err   error
+}
+
+func NewTBPDriver(bot *TBPBot) *TBPDriver {
+	return &TBPDriver{bot: bot, result: make(chan tbpResult, 1)}
+}
+
+// NewTBPBotInput creates a virtual player whose moves come from a TBP bot.
+func NewTBPBotInput(settings *Settings, d *TBPDriver) *BotInput {
+	return newBotInput(settings, d.choose)
+}
+
+// choose is called every tick until the bot has answered, so the game keeps
+// running while the bot thinks.
+func (d *TBPDriver) choose(b *Board) (Placement, bool) {
+	if d.Err != nil {
+		return d.fallback(b)
+	}
+
+	if !d.thinking {
+		messages := d.sync(b)
+		d.thinking = true
+
+		go func() {
+			for _, msg := range messages {
+				if err := d.bot.send(msg); err != nil {
+					d.result <- tbpResult{err: err}
+					return
+				}
+			}
+
+			moves, err := d.bot.suggest()
+			d.result <- tbpResult{moves, err}
+		}()
+
+		return Placement{}, false
+	}
+
+	var r tbpResult
+	select {
+	case r = <-d.result:
+		d.thinking = false
+	default:
+		return Placement{}, false
+	}
+
+	if r.err != nil {
+		d.Err = r.err
+		return d.fallback(b)
+	}
+
+	placements := b.Placements()
+	for _, move := range r.moves {
+		cells, ok := tbpCells(move.Location, b.field.Height())
+		if !ok {
+			continue
+		}
+
+		for _, p := range placements {
+			if pieceCells(&p.piece) == cells {
+				d.Played++
+				return d.play(b, p, move)
+			}
+		}
+	}
+
+	return d.fallback(b)
+}
+
+// fallback places the piece with the built-in heuristic, telling the bot
+// where it went.
+func (d *TBPDriver) fallback(b *Board) (Placement, bool) {
+	p, ok := b.BestPlacement(defaultBotWeights)
+	if !ok {
+		return p, false
+	}
+	d.Fallbacks++
+
+	move, ok := tbpMove(&p.piece, b.field.Height())
+	if !ok || d.Err != nil {
+		return p, true
+	}
+
+	return d.play(b, p, move)
+}
+
+func (d *TBPDriver) play(b *Board, p Placement, move TBPMove) (Placement, bool) {
+	if err := d.bot.send(tbpPlay{Type: "play", Move: move}); err != nil {
+		d.Err = err
+	}
+	d.expected, _, _ = b.fieldAfter(&p.piece, d.expected)
+
+	return p, true
+}
+
+// sync returns the messages that bring the bot up to date with the board.
+func (d *TBPDriver) sync(b *Board) []any {
+	spawned := b.piecesPlaced + 1 + len(b.pieceQueue)
+
+	if b != d.board || !slices.Equal(d.expected, b.field.rows) {
+		var messages []any
+		if d.board != nil {
+			messages = append(messages, tbpCommand{Type: "stop"})
+		}
+
+		d.board = b
+		d.told = spawned
+
+		return append(messages, tbpStartMessage(b))
+	}
+
+	var messages []any
+	for ; d.told < spawned; d.told++ {
+		next := b.pieceQueue[len(b.pieceQueue)-(spawned-d.told)]
+		messages = append(messages, tbpNewPiece{Type: "new_piece", Piece: tbpPieces[next.piece.kind]})
+	}
+
+	return messages
+}
+
+func tbpStartMessage(b *Board) tbpStart {
+	msg := tbpStart{
+		Type:       "start",
+		Queue:      []string{tbpPieces[b.currentPiece.piece.kind]},
+		Combo:      max(b.combo, 0),
+		BackToBack: b.backToBack,
+		Board:      make([][]*string, tbpBoardHeight),
+	}
+
+	for _, next := range b.pieceQueue {
+		msg.Queue = append(msg.Queue, tbpPieces[next.piece.kind])
+	}
+
+	for y := range msg.Board {
+		msg.Board[y] = make([]*string, b.field.Width())
+		if y >= b.field.Height() {
+			continue
+		}
+
+		for x := range msg.Board[y] {
+			if fy := b.field.Height() - 1 - y; b.field.Color(x, fy) != nil {
+				name := tbpCellName(b.field.Kind(x, fy))
+				msg.Board[y][x] = &name
+			}
+		}
+	}
+
+	return msg
+}
+
+// tbpCellName names a settled block by the kind of piece it came from.
+// Garbage is "G".
+func tbpCellName(kind int) string {
+	if kind >= 0 && kind < len(tbpPieces) {
+		return tbpPieces[kind]
+	}
+
+	return "G"
+}
+
+// tbpCells returns the field cells covered by a TBP location as sorted
+// (column, row) pairs, with rows counted from the top of a field of the
+// given height.
+func tbpCells(loc TBPLocation, height int) ([4][2]int, bool) {
+	kind := slices.Index(tbpPieces, loc.Type)
+	rotations := slices.Index(tbpOrientations, loc.Orientation)
+	if kind < 0 || rotations < 0 {
+		return [4][2]int{}, false
+	}
+
+	var cells [4][2]int
+	for i, offset := range tbpShapes[kind] {
+		dx, dy := offset[0], offset[1]
+		for range rotations {
+			dx, dy = dy, -dx
+		}
+		cells[i] = [2]int{loc.X + dx, height - 1 - (loc.Y + dy)}
+	}
+	slices.SortFunc(cells[:], compareCells)
+
+	return cells, true
+}
+
+// tbpMove finds the TBP location of a piece that covers the same cells.
+func tbpMove(p *FallingPiece, height int) (TBPMove, bool) {
+	cells := pieceCells(p)
+	loc := TBPLocation{Type: tbpPieces[p.piece.kind]}
+
+	// The centre is never more than two cells away from any cell of the piece
+	x0, y0 := cells[0][0], height-1-cells[0][1]
+	for _, loc.Orientation = range tbpOrientations {
+		for loc.X = x0 - 2; loc.X <= x0+2; loc.X++ {
+			for loc.Y = y0 - 2; loc.Y <= y0+2; loc.Y++ {
+				if got, _ := tbpCells(loc, height); got == cells {
+					return TBPMove{Location: loc, Spin: "none"}, true
+				}
+			}
+		}
+	}
+
+	return TBPMove{}, false
+}
+
+func pieceCells(p *FallingPiece) [4][2]int {
+	var cells [4][2]int
+	for i, tile := range p.getTiles() {
+		cells[i] = [2]int{int(p.x) + tile.x, int(p.y) + tile.y}
+	}
+	slices.SortFunc(cells[:], compareCells)
+
+	return cells
+}
+
+func compareCells(a, b [2]int) int {
+	if a[1] != b[1] {
+		return a[1] - b[1]
+	}
+
+	return a[0] - b[0]
+}
+
+// Close stops the bot process.
+func (d *TBPDriver) Close() error {
+	return d.bot.Close()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestTBPMockBot isn't a real test. The TBP tests run the test binary again
+// with this test selected to get a bot process to talk to.
+func TestTBPMockBot(t *testing.T) {
+	if os.Getenv("MLETRIS_TBP_MOCK") != "1" {
+		t.Skip("only runs as the mock bot process")
+	}
+
+	runMockTBPBot()
+	os.Exit(0)
+}
+
+// runMockTBPBot keeps its own copy of the board from the messages it gets
+// and suggests the placements of the built-in heuristic.
+func runMockTBPBot() {
+	in := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	b := NewBoardWithSeed(rows, cols, 0)
+	var queue []PieceKind
+
+	out.Encode(TBPMessage{Type: "info", Name: "mock", Version: "1", Author: "mletris"})
+
+	for in.Scan() {
+		var msg struct {
+			Type  string      `json:"type"`
+			Queue []string    `json:"queue"`
+			Board [][]*string `json:"board"`
+			Piece string      `json:"piece"`
+			Move  TBPMove     `json:"move"`
+		}
+		json.Unmarshal(in.Bytes(), &msg)
+
+		switch msg.Type {
+		case "rules":
+			out.Encode(TBPMessage{Type: "ready"})
+		case "start":
+			queue = queue[:0]
+			for _, name := range msg.Queue {
+				queue = append(queue, PieceKind(slices.Index(tbpPieces, name)))
+			}
+			for y := range rows {
+				b.field.ClearRow(y)
+				for x := range cols {
+					if msg.Board[rows-1-y][x] != nil {
+						b.field.Set(x, y, colorGarbage, garbageKind)
+					}
+				}
+			}
+		case "new_piece":
+			queue = append(queue, PieceKind(slices.Index(tbpPieces, msg.Piece)))
+		case "suggest":
+			b.currentPiece = &FallingPiece{piece: b.tiles[queue[0]], x: spawnX, y: 1}
+			best, _ := b.BestPlacement(defaultBotWeights)
+			move, _ := tbpMove(&best.piece, rows)
+
+			// A move that can't be reached comes first, so the driver has
+			// to look further down the list
+			unreachable := TBPMove{Location: TBPLocation{Type: move.Location.Type, Orientation: "north", X: -5, Y: 0}}
+			out.Encode(TBPMessage{Type: "suggestion", Moves: []TBPMove{unreachable, move}})
+		case "play":
+			cells, _ := tbpCells(msg.Move.Location, rows)
+			for _, cell := range cells {
+				b.field.Set(cell[0], cell[1], colorGarbage, garbageKind)
+			}
+			b.field.clearFullRows(nil)
+			queue = queue[1:]
+		case "quit":
+			return
+		}
+	}
+}
+
+func startMockTBPBot(t *testing.T) *TBPDriver {
+	t.Helper()
+	t.Setenv("MLETRIS_TBP_MOCK", "1")
+
+	bot, err := StartTBPBot(os.Args[0], "-test.run=^TestTBPMockBot$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bot.Info.Name != "mock" {
+		t.Errorf("bot name = %q, want mock", bot.Info.Name)
+	}
+
+	d := NewTBPDriver(bot)
+	t.Cleanup(func() { d.Close() })
+
+	return d
+}
+
+func TestTBPMove_RoundTrip(t *testing.T) {
+	b := NewBoardWithSeed(rows, cols, 1)
+
+	for kind := range b.tiles {
+		b.currentPiece = &FallingPiece{piece: b.tiles[kind], x: spawnX, y: 1}
+
+		for _, p := range b.Placements() {
+			move, ok := tbpMove(&p.piece, rows)
+			if !ok {
+				t.Fatalf("%s in column %d state %d has no TBP location", tbpPieces[kind], p.X, p.State)
+			}
+
+			cells, _ := tbpCells(move.Location, rows)
+			if cells != pieceCells(&p.piece) {
+				t.Errorf("%s: %v covers %v, want %v", tbpPieces[kind], move.Location, cells, pieceCells(&p.piece))
+			}
+		}
+	}
+}
+
+func TestTBPDriver_PlaysWithMockBot(t *testing.T) {
+	d := startMockTBPBot(t)
+	settings := DefaultSettings()
+	settings.BotSpeed = maxBotSpeed
+	input := NewTBPBotInput(settings, d)
+	b := NewBoardWithSeed(rows, cols, 3)
+
+	attacked := false
+	deadline := time.Now().Add(20 * time.Second)
+	for b.piecesPlaced < 100 && !b.gameOver {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out after %d pieces", b.piecesPlaced)
+		}
+
+		// Garbage the bot doesn't know about makes the driver resend the board
+		if b.piecesPlaced == 40 && !attacked {
+			b.QueueGarbage(3)
+			attacked = true
+		}
+
+		input.Update(b)
+		if d.thinking {
+			time.Sleep(50 * time.Microsecond)
+			continue
+		}
+		b.Tick()
+	}
+
+	if d.Err != nil {
+		t.Fatal(d.Err)
+	}
+	if b.gameOver {
+		t.Errorf("topped out after %d pieces", b.piecesPlaced)
+	}
+	if d.Fallbacks != 0 {
+		t.Errorf("%d pieces were not placed by the bot", d.Fallbacks)
+	}
+	if d.Played < 100 {
+		t.Errorf("bot played %d moves, want 100", d.Played)
+	}
+	if b.GarbageRowsLeft() == 0 && b.garbageCleared == 0 {
+		t.Errorf("garbage never rose")
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+type terminal struct{}
+
+func openTerminal(f *os.File) (*terminal, error) {
+	return nil, errors.New("the terminal frontend only runs on Linux, macOS and the BSDs")
+}
+
+func (t *terminal) Restore() error {
+	return nil
+}
+
+func (t *terminal) Size() (int, int, error) {
+	return 0, 0, errors.New("not a terminal")
+}
+
+func notifyResize(c chan<- os.Signal) {}
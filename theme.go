@@ -0,0 +1,307 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+//go:embed themes/*.json
+var builtinThemeFiles embed.FS
+
+const (
+	defaultTheme      = "Synthwave"
+	themePollInterval = time.Second
+	garbagePieceName  = "garbage"
+)
+
+// HexColor is a colour written as #rrggbb or #rrggbbaa in theme files.
+type HexColor color.RGBA
+
+func (c *HexColor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	var rgba color.RGBA
+	var n int
+	var err error
+	switch len(s) {
+	case 7:
+		rgba.A = 0xff
+		n, err = fmt.Sscanf(s, "#%02x%02x%02x", &rgba.R, &rgba.G, &rgba.B)
+		n++
+	case 9:
+		n, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &rgba.R, &rgba.G, &rgba.B, &rgba.A)
+	}
+	if err != nil || n != 4 {
+		return fmt.Errorf("invalid colour %q, want #rrggbb or #rrggbbaa", s)
+	}
+
+	*c = HexColor(rgba)
+	return nil
+}
+
+func (c HexColor) MarshalJSON() ([]byte, error) {
+	if c.A == 0xff {
+		return json.Marshal(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+
+	return json.Marshal(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A))
+}
+
+// Theme sets the colours and font of the game. Piece colours are only
+// used for drawing: the board keeps the default colours, so players with
+// different themes still play the same game.
+type Theme struct {
+	Name       string              `json:"name"`
+	Background HexColor            `json:"background"`
+	Board      HexColor            `json:"board"`
+	Grid       *HexColor           `json:"grid,omitempty"` // a darker board colour when missing
+	Text       HexColor            `json:"text"`
+	Pieces     map[string]HexColor `json:"pieces"` // by piece name, plus garbage
+	// Font is a TTF or OTF file, relative to the theme file. The built-in
+	// font is used when it is empty.
+	Font      string  `json:"font,omitempty"`
+	TextScale float64 `json:"text_scale,omitempty"` // text sizes are multiplied by this
+
+	dir string // where the theme was loaded from, for its font
+}
+
+// ParseTheme reads a theme, checking it has a name and every colour.
+func ParseTheme(data []byte) (*Theme, error) {
+	t := &Theme{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, err
+	}
+
+	if t.Name == "" {
+		return nil, errors.New("theme has no name")
+	}
+	for _, name := range append(pieceNames[:], garbagePieceName) {
+		if _, ok := t.Pieces[name]; !ok {
+			return nil, fmt.Errorf("theme %s has no colour for %s", t.Name, name)
+		}
+	}
+	if t.TextScale <= 0 {
+		t.TextScale = 1
+	}
+
+	return t, nil
+}
+
+// GridColor returns the colour of the grid lines.
+func (t *Theme) GridColor() color.RGBA {
+	if t.Grid != nil {
+		return color.RGBA(*t.Grid)
+	}
+
+	b := t.Board
+	return color.RGBA{uint8(float32(b.R) * 0.8), uint8(float32(b.G) * 0.8), uint8(float32(b.B) * 0.8), b.A}
+}
+
+// TileColor returns the colour to draw a tile of colour c and a kind in.
+// Tiles that are neither pieces nor garbage keep their colour.
+func (t *Theme) TileColor(c color.Color, kind int) color.Color {
+	switch {
+	case kind == garbageKind:
+		return color.RGBA(t.Pieces[garbagePieceName])
+	case kind >= 0:
+		return color.RGBA(t.Pieces[pieceNames[kind]])
+	}
+
+	return c
+}
+
+// FontPath returns the file of the theme's font, or "" for the built-in one.
+func (t *Theme) FontPath() string {
+	if t.Font == "" || t.dir == "" {
+		return ""
+	}
+
+	return filepath.Join(t.dir, t.Font)
+}
+
+// tileKind returns the PieceKind a tile colour belongs to, garbageKind for
+// garbage and -1 for any other colour or none.
+func tileKind(c color.Color) int {
+	if c == nil {
+		return -1
+	}
+	for kind, pc := range pieceColors {
+		if sameColor(c, pc) {
+			return kind
+		}
+	}
+	if sameColor(c, colorGarbage) {
+		return garbageKind
+	}
+
+	return -1
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+// Themes are the built-in themes and the ones in a directory. Poll reloads
+// the directory when its files change, so designers see their edits live.
+// A theme in the directory replaces the built-in theme with the same name.
+type Themes struct {
+	dir      string
+	builtin  []*Theme
+	loaded   []*Theme
+	modTimes map[string]time.Time
+	polled   time.Time
+}
+
+// NewThemes loads the built-in themes and the themes in dir. An empty dir
+// only has the built-in themes.
+func NewThemes(dir string) (*Themes, error) {
+	s := &Themes{dir: dir}
+
+	entries, err := builtinThemeFiles.ReadDir("themes")
+	if err != nil {
+		return s, err
+	}
+	for _, entry := range entries {
+		data, err := builtinThemeFiles.ReadFile("themes/" + entry.Name())
+		if err != nil {
+			return s, err
+		}
+		t, err := ParseTheme(data)
+		if err != nil {
+			return s, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		s.builtin = append(s.builtin, t)
+	}
+	// The default theme comes first
+	if i := slices.IndexFunc(s.builtin, func(t *Theme) bool { return t.Name == defaultTheme }); i > 0 {
+		t := s.builtin[i]
+		s.builtin = slices.Insert(slices.Delete(s.builtin, i, i+1), 0, t)
+	}
+
+	_, err = s.Reload()
+	return s, err
+}
+
+// Poll reloads the directory if it hasn't been checked for a while. It
+// returns true when a theme changed.
+func (s *Themes) Poll(now time.Time) (bool, error) {
+	if now.Sub(s.polled) < themePollInterval {
+		return false, nil
+	}
+	s.polled = now
+
+	return s.Reload()
+}
+
+// Reload reads the themes in the directory again if any file was added,
+// changed or removed. It returns true when they did.
+func (s *Themes) Reload() (bool, error) {
+	if s.dir == "" {
+		return false, nil
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		entries, err = nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	modTimes := map[string]time.Time{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		modTimes[entry.Name()] = info.ModTime()
+	}
+
+	if s.modTimes != nil && sameModTimes(s.modTimes, modTimes) {
+		return false, nil
+	}
+	s.modTimes = modTimes
+
+	var loaded []*Theme
+	var errs []error
+	for name := range modTimes {
+		path := filepath.Join(s.dir, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			var t *Theme
+			if t, err = ParseTheme(data); err == nil {
+				t.dir = s.dir
+				loaded = append(loaded, t)
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+	slices.SortFunc(loaded, func(a, b *Theme) int { return strings.Compare(a.Name, b.Name) })
+	s.loaded = loaded
+
+	return true, errors.Join(errs...)
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, t := range a {
+		if !b[name].Equal(t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Named returns the theme with the name, or the default theme.
+func (s *Themes) Named(name string) *Theme {
+	for _, t := range s.loaded {
+		if t.Name == name {
+			return t
+		}
+	}
+	for _, t := range s.builtin {
+		if t.Name == name {
+			return t
+		}
+	}
+	if name != defaultTheme {
+		return s.Named(defaultTheme)
+	}
+
+	return s.builtin[0]
+}
+
+// Names lists the themes, built-in ones first.
+func (s *Themes) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, t := range append(s.builtin[:len(s.builtin):len(s.builtin)], s.loaded...) {
+		if !seen[t.Name] {
+			seen[t.Name] = true
+			names = append(names, t.Name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinThemes(t *testing.T) {
+	themes, err := NewThemes("")
+	if err != nil {
+		t.Fatalf("Could not load the built-in themes: %v", err)
+	}
+
+	names := themes.Names()
+	if len(names) != 4 || names[0] != defaultTheme {
+		t.Fatalf("Expected 4 themes starting with %s, got %v", defaultTheme, names)
+	}
+
+	// The default theme draws the pieces in their own colours
+	synthwave := themes.Named(defaultTheme)
+	for _, c := range append(pieceColors[:], colorGarbage) {
+		if got := synthwave.TileColor(c, tileKind(c)); !sameColor(got, c) {
+			t.Errorf("Expected %v to stay the same, got %v", c, got)
+		}
+	}
+
+	gameBoy := themes.Named("Game Boy")
+	if got := gameBoy.TileColor(colorI, int(PieceI)); !sameColor(got, color.RGBA{0x0f, 0x38, 0x0f, 0xff}) {
+		t.Errorf("Expected the Game Boy I piece to be dark green, got %v", got)
+	}
+
+	if themes.Named("missing") != synthwave {
+		t.Errorf("Expected an unknown theme to fall back to %s", defaultTheme)
+	}
+}
+
+func TestPalettes(t *testing.T) {
+	themes, err := NewThemes("")
+	if err != nil {
+		t.Fatalf("Could not load the built-in themes: %v", err)
+	}
+	gameBoy := themes.Named("Game Boy")
+
+	for _, p := range palettes {
+		seen := map[color.RGBA]bool{}
+		for kind, c := range p.Pieces {
+			if seen[c] {
+				t.Errorf("%s: colour %v is used twice", p.Name, c)
+			}
+			seen[c] = true
+
+			if got := p.TileColor(gameBoy, pieceColors[0], kind); got != c {
+				t.Errorf("%s: expected kind %d to be %v, got %v", p.Name, kind, c, got)
+			}
+		}
+	}
+
+	// Without a palette the theme's colours are used
+	var off *Palette
+	if got := off.TileColor(gameBoy, colorI, int(PieceI)); got != gameBoy.TileColor(colorI, int(PieceI)) {
+		t.Errorf("Expected the theme colour without a palette, got %v", got)
+	}
+
+	if paletteNamed(paletteOff) != nil || paletteNamed("Tritanopia") == nil {
+		t.Errorf("Expected only real palettes to be found by name")
+	}
+}
+
+func TestParseTheme_Errors(t *testing.T) {
+	tests := map[string]string{
+		"bad colour":    `{"name": "x", "background": "red"}`,
+		"no name":       `{"background": "#000000"}`,
+		"missing piece": `{"name": "x", "pieces": {"I": "#000000"}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseTheme([]byte(data)); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestThemes_HotReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mine.json")
+	data, err := builtinThemeFiles.ReadFile("themes/nes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(text string, modTime time.Time) {
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	write(strings.Replace(string(data), `"NES"`, `"Mine"`, 1), start)
+
+	themes, err := NewThemes(dir)
+	if err != nil {
+		t.Fatalf("Could not load the themes: %v", err)
+	}
+	if themes.Named("Mine").Name != "Mine" {
+		t.Fatalf("Expected the theme from the directory, got %v", themes.Names())
+	}
+
+	if changed, _ := themes.Poll(start); changed {
+		t.Errorf("Expected no reload when nothing changed")
+	}
+
+	write(strings.Replace(string(data), `"NES"`, `"Renamed"`, 1), start.Add(time.Minute))
+
+	if changed, _ := themes.Poll(start.Add(themePollInterval / 2)); changed {
+		t.Errorf("Expected no reload before the poll interval")
+	}
+	if changed, err := themes.Poll(start.Add(themePollInterval)); !changed || err != nil {
+		t.Fatalf("Expected the changed theme to be reloaded (%v)", err)
+	}
+	if themes.Named("Renamed").Name != "Renamed" {
+		t.Errorf("Expected the renamed theme, got %v", themes.Names())
+	}
+}
@@ -10,12 +10,38 @@ var (
 	colorZ = color.RGBA{0xff, 0x00, 0x00, 0xff} // Red
 	colorJ = color.RGBA{0x00, 0x00, 0xff, 0xff} // Blue
 	colorL = color.RGBA{0xff, 0xa5, 0x00, 0xff} // Orange
+
+	colorGarbage = color.RGBA{0x80, 0x80, 0x80, 0xff} // Grey
+
+	// pieceColors are indexed by PieceKind.
+	pieceColors = [...]color.Color{colorI, colorO, colorT, colorS, colorZ, colorJ, colorL}
+)
+
+// PieceKind identifies a tetromino. The values follow the order of
+// buildTiles.
+type PieceKind int
+
+const (
+	PieceI PieceKind = iota
+	PieceO
+	PieceT
+	PieceS
+	PieceZ
+	PieceJ
+	PieceL
 )
 
+var pieceNames = [...]string{"I", "O", "T", "S", "Z", "J", "L"}
+
+func (k PieceKind) String() string {
+	return pieceNames[k]
+}
+
 func buildTiles() []Piece {
 	return []Piece{
 		// I piece (line)
 		{
+			kind: PieceI,
 			data: [][]Tile{
 				// x
 				// x
@@ -39,6 +65,7 @@ func buildTiles() []Piece {
 
 		// O piece (square)
 		{
+			kind: PieceO,
 			data: [][]Tile{
 				// xx
 				// xx
@@ -53,6 +80,7 @@ func buildTiles() []Piece {
 
 		// T piece (purple)
 		{
+			kind: PieceT,
 			data: [][]Tile{
 				//  x
 				// xxx
@@ -93,6 +121,7 @@ func buildTiles() []Piece {
 
 		// S piece (green)
 		{
+			kind: PieceS,
 			data: [][]Tile{
 				//  xx
 				// xx
@@ -116,6 +145,7 @@ func buildTiles() []Piece {
 
 		// Z piece (red)
 		{
+			kind: PieceZ,
 			data: [][]Tile{
 				// xx
 				//  xx
@@ -139,6 +169,7 @@ func buildTiles() []Piece {
 
 		// J piece (blue)
 		{
+			kind: PieceJ,
 			data: [][]Tile{
 				//  x
 				//  x
@@ -179,6 +210,7 @@ func buildTiles() []Piece {
 
 		// L piece (orange)
 		{
+			kind: PieceL,
 			data: [][]Tile{
 				// x
 				// x
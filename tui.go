@@ -0,0 +1,613 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// termHoldTicks is how long a move key counts as held after the
+	// terminal last sent it. Terminals only send key presses, repeating
+	// them while a key is held, so a key is released once its repeats stop.
+	termHoldTicks = 4
+
+	// termEscapeTicks is how long a lone Esc waits for the rest of an escape
+	// sequence, which can arrive in a later read, before it is the Esc key.
+	termEscapeTicks = 3
+
+	tuiFrameTicks = 2  // the terminal is redrawn at most every this many ticks
+	tuiPanelWidth = 30 // room for the score and help right of the board
+)
+
+// Escape sequences of the terminal frontend.
+const (
+	ansiEnter      = "\x1b[?1049h\x1b[?25l" // alternate screen, hidden cursor
+	ansiLeave      = "\x1b[0m\x1b[?25h\x1b[?1049l"
+	ansiHome       = "\x1b[H"
+	ansiClear      = "\x1b[2J"
+	ansiClearLine  = "\x1b[K"
+	ansiClearBelow = "\x1b[J"
+	ansiDefaultBG  = "\x1b[49m"
+)
+
+// termKey is a key read from the terminal.
+type termKey int
+
+const (
+	termKeyNone termKey = iota
+	termKeyLeft
+	termKeyRight
+	termKeyDown
+	termKeyUp
+	termKeySpace
+	termKeyEnter
+	termKeyEscape
+	termKeyPause
+	termKeyQuit
+)
+
+// termButtons are the game buttons of the keys.
+var termButtons = map[termKey]Buttons{
+	termKeyLeft:  ButtonLeft,
+	termKeyRight: ButtonRight,
+	termKeyDown:  ButtonSoftDrop,
+	termKeyUp:    ButtonRotate,
+	termKeySpace: ButtonHardDrop,
+}
+
+// termRepeating are the buttons that repeat while held.
+const termRepeating = ButtonLeft | ButtonRight | ButtonSoftDrop
+
+// parseTermKeys decodes the keys in the bytes read from a terminal. It
+// returns the start of an escape sequence cut off at the end, including a
+// lone Esc, to be parsed again with the next bytes. Other keys and
+// sequences are skipped.
+func parseTermKeys(data []byte) ([]termKey, []byte) {
+	var keys []termKey
+	for len(data) > 0 {
+		if data[0] != 0x1b {
+			if k := termKeyOf(data[0]); k != termKeyNone {
+				keys = append(keys, k)
+			}
+			data = data[1:]
+			continue
+		}
+
+		if len(data) == 1 {
+			return keys, data
+		}
+
+		// Esc on its own, not the start of a sequence
+		if data[1] != '[' && data[1] != 'O' {
+			keys = append(keys, termKeyEscape)
+			data = data[1:]
+			continue
+		}
+
+		end := 2
+		for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+			end++
+		}
+		if end == len(data) {
+			return keys, data
+		}
+
+		switch data[end] {
+		case 'A':
+			keys = append(keys, termKeyUp)
+		case 'B':
+			keys = append(keys, termKeyDown)
+		case 'C':
+			keys = append(keys, termKeyRight)
+		case 'D':
+			keys = append(keys, termKeyLeft)
+		}
+		data = data[end+1:]
+	}
+
+	return keys, nil
+}
+
+// termKeyReader decodes the keys read from a terminal, keeping what is cut
+// off at the end of a read for the next one.
+type termKeyReader struct {
+	pending []byte
+	waited  int // ticks a lone Esc has waited for more bytes
+}
+
+// read returns the keys in the bytes read from the terminal.
+func (r *termKeyReader) read(data []byte) []termKey {
+	var keys []termKey
+	keys, r.pending = parseTermKeys(append(r.pending, data...))
+	r.waited = 0
+
+	return keys
+}
+
+// tick is called every tick. It returns the Esc key once a lone Esc has
+// waited termEscapeTicks without the rest of a sequence arriving.
+func (r *termKeyReader) tick() termKey {
+	if len(r.pending) != 1 {
+		return termKeyNone
+	}
+
+	r.waited++
+	if r.waited < termEscapeTicks {
+		return termKeyNone
+	}
+	r.pending = nil
+
+	return termKeyEscape
+}
+
+func termKeyOf(c byte) termKey {
+	switch c {
+	case 'a', 'A', 'h':
+		return termKeyLeft
+	case 'd', 'D', 'l':
+		return termKeyRight
+	case 's', 'S', 'j':
+		return termKeyDown
+	case 'w', 'W', 'k':
+		return termKeyUp
+	case ' ':
+		return termKeySpace
+	case '\r', '\n':
+		return termKeyEnter
+	case 'p', 'P':
+		return termKeyPause
+	case 'q', 'Q', 0x03: // Ctrl-C
+		return termKeyQuit
+	}
+
+	return termKeyNone
+}
+
+// termInput turns the keys read from a terminal into the buttons held each
+// tick, for a Controller. Moves and soft drop stay held while the terminal
+// repeats them, so DAS and ARR work as in the window. Every rotate and hard
+// drop key is a press of its own, even when several arrive in one tick.
+type termInput struct {
+	since   [buttonCount]int // ticks since a repeating button was last sent
+	presses [buttonCount]int // rotates and hard drops not pressed yet
+	last    Buttons
+}
+
+func newTermInput() *termInput {
+	t := &termInput{}
+	for i := range t.since {
+		t.since[i] = termHoldTicks
+	}
+
+	return t
+}
+
+// press records a button sent by the terminal.
+func (t *termInput) press(button Buttons) {
+	for i := range buttonCount {
+		switch {
+		case button != 1<<i:
+		case button&termRepeating != 0:
+			t.since[i] = 0
+		default:
+			t.presses[i]++
+		}
+	}
+}
+
+// Buttons returns the buttons held this tick. It is called once a tick.
+func (t *termInput) Buttons() Buttons {
+	var held Buttons
+	for i := range buttonCount {
+		button := Buttons(1 << i)
+		switch {
+		case button&termRepeating != 0:
+			if t.since[i] < termHoldTicks {
+				held |= button
+			}
+			t.since[i] = min(t.since[i]+1, termHoldTicks)
+		case t.last&button != 0:
+			// Released for a tick, so the next press is seen
+		case t.presses[i] > 0:
+			t.presses[i]--
+			held |= button
+		}
+	}
+	t.last = held
+
+	return held
+}
+
+// tuiGame is a game played in the terminal.
+type tuiGame struct {
+	mode       Mode
+	board      *Board
+	controller *Controller
+	input      *termInput
+	historyDir string // sessions are logged here, unless it is empty
+	recorded   bool
+	quit       bool
+	err        error // from logging a session, reported after the game
+}
+
+func newTUIGame(mode Mode, settings *Settings, historyDir string) *tuiGame {
+	g := &tuiGame{
+		mode:       mode,
+		controller: NewController(settings),
+		historyDir: historyDir,
+	}
+	g.restart()
+
+	return g
+}
+
+func (g *tuiGame) restart() {
+	g.board = NewBoard(rows, cols)
+	g.mode.Setup(g.board)
+	g.controller.Reset()
+	g.input = newTermInput()
+	g.recorded = false
+}
+
+func (g *tuiGame) over() bool {
+	return g.board.gameOver || g.board.finished
+}
+
+// handleKey acts on a key read from the terminal.
+func (g *tuiGame) handleKey(k termKey) {
+	switch {
+	case k == termKeyQuit:
+		g.quit = true
+	case g.over():
+		if k == termKeyEnter {
+			g.restart()
+		} else if k == termKeyEscape {
+			g.quit = true
+		}
+	case k == termKeyPause || k == termKeyEscape:
+		g.board.TogglePause()
+	case !g.board.paused:
+		g.input.press(termButtons[k])
+	}
+}
+
+// tick plays a tick of the game, like the play scene of the window.
+func (g *tuiGame) tick() {
+	b := g.board
+	if g.over() {
+		if !g.recorded && g.historyDir != "" {
+			g.err = saveSession(g.historyDir, newSession(g.mode, b, g.controller.settings, time.Now()))
+		}
+		g.recorded = true
+		return
+	}
+
+	g.controller.Apply(b, g.input.Buttons())
+	if !b.paused {
+		playTick(b, g.mode)
+	}
+}
+
+// tuiStyle turns the colours of the theme and palette into escape
+// sequences, in 24-bit colour when the terminal says it supports it and in
+// the 256 colour palette otherwise.
+type tuiStyle struct {
+	theme     *Theme
+	palette   *Palette
+	trueColor bool
+}
+
+func (s *tuiStyle) tile(c color.Color, kind int) color.Color {
+	if c == nil {
+		return color.RGBA(s.theme.Board)
+	}
+
+	return s.palette.TileColor(s.theme, c, kind)
+}
+
+func (s *tuiStyle) fg(c color.Color) string {
+	return s.ansi(38, c)
+}
+
+func (s *tuiStyle) bg(c color.Color) string {
+	return s.ansi(48, c)
+}
+
+func (s *tuiStyle) ansi(layer int, c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	r, g, b = r>>8, g>>8, b>>8
+	if s.trueColor {
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", layer, r, g, b)
+	}
+
+	// The 6x6x6 colour cube of the 256 colours
+	cube := func(v uint32) uint32 { return (v*5 + 127) / 255 }
+	return fmt.Sprintf("\x1b[%d;5;%dm", layer, 16+36*cube(r)+6*cube(g)+cube(b))
+}
+
+// frame draws the game for a terminal of width by height characters. A
+// tile is two characters wide when the board fits the height, and half a
+// character high, drawn with half blocks, when it does not.
+func (g *tuiGame) frame(width, height int, style *tuiStyle) string {
+	b := g.board
+	fieldRows, fieldCols := b.field.Height(), b.field.Width()
+
+	half := height < fieldRows+2
+	lines, boardWidth := fieldRows, 2*fieldCols
+	if half {
+		lines, boardWidth = (fieldRows+1)/2, fieldCols
+	}
+	if height < lines+2 || width < boardWidth+2+tuiPanelWidth {
+		message := "Terminal too small, Q quits"
+		return message[:min(len(message), width)] + ansiClearLine + ansiClearBelow
+	}
+
+	// The settled tiles with the falling piece on top
+	tiles := make([][]color.Color, fieldRows)
+	for y := range tiles {
+		tiles[y] = make([]color.Color, fieldCols)
+		for x := range tiles[y] {
+			tiles[y][x] = style.tile(b.field.Color(x, y), b.field.Kind(x, y))
+		}
+	}
+	if p := b.currentPiece; p != nil && !b.gameOver {
+		for _, tile := range p.getTiles() {
+			x, y := int(p.x)+tile.x, int(p.y)+tile.y
+			if y >= 0 && y < fieldRows && x >= 0 && x < fieldCols {
+				tiles[y][x] = style.tile(tile.color, int(p.piece.kind))
+			}
+		}
+	}
+
+	text := ansiDefaultBG + style.fg(color.RGBA(style.theme.Text))
+	panel := g.panel(style, text)
+
+	var sb strings.Builder
+	for i := range lines + 2 {
+		sb.WriteString(text)
+		switch {
+		case i == 0:
+			sb.WriteString("┌" + strings.Repeat("─", boardWidth) + "┐")
+		case i == lines+1:
+			sb.WriteString("└" + strings.Repeat("─", boardWidth) + "┘")
+		case half:
+			sb.WriteString("│")
+			top, bottom := tiles[2*(i-1)], tiles[min(2*i-1, fieldRows-1)]
+			for x := range fieldCols {
+				sb.WriteString(style.fg(top[x]) + style.bg(bottom[x]) + "▀")
+			}
+			sb.WriteString(text + "│")
+		default:
+			sb.WriteString("│")
+			for _, c := range tiles[i-1] {
+				sb.WriteString(style.bg(c) + "  ")
+			}
+			sb.WriteString(text + "│")
+		}
+
+		if i < len(panel) {
+			sb.WriteString(" " + panel[i])
+		}
+		sb.WriteString(ansiClearLine)
+		if i < lines+1 {
+			sb.WriteString("\r\n")
+		}
+	}
+	sb.WriteString(ansiClearBelow)
+
+	return sb.String()
+}
+
+// panel returns the lines right of the board: the score, the next piece,
+// what the game waits for and the keys.
+func (g *tuiGame) panel(style *tuiStyle, text string) []string {
+	b := g.board
+	lines := []string{
+		g.mode.Title(),
+		"",
+		fmt.Sprintf("%-8s%d", "SCORE", b.Score),
+		fmt.Sprintf("%-8s%d", "LEVEL", b.Level),
+	}
+	for _, item := range g.mode.HUD(b) {
+		lines = append(lines, fmt.Sprintf("%-8s%s", item.Label, item.Value))
+	}
+
+	lines = append(lines, "", "NEXT")
+	if len(b.pieceQueue) > 0 {
+		next := b.pieceQueue[0]
+		// The same 4x4 box as in the window, without its empty rows
+		var box [4][4]color.Color
+		for _, tile := range next.getTiles() {
+			box[tile.y+1][tile.x+1] = style.tile(tile.color, int(next.piece.kind))
+		}
+		for _, row := range box {
+			if row == [4]color.Color{} {
+				continue
+			}
+			line := ""
+			for _, c := range row {
+				if c == nil {
+					line += text + "  "
+				} else {
+					line += style.bg(c) + "  "
+				}
+			}
+			lines = append(lines, line+text)
+		}
+	}
+
+	lines = append(lines, "")
+	switch {
+	case b.gameOver || b.finished:
+		status := "GAME OVER"
+		if b.finished {
+			status = g.mode.Banner()
+		}
+		lines = append(lines, status, "Enter: play again  Esc: quit")
+	case b.paused:
+		lines = append(lines, "PAUSED", "P: resume  Q: quit")
+	case b.countdown > 0:
+		lines = append(lines, fmt.Sprintf("%d", (b.countdown+ticksPerSecond-1)/ticksPerSecond))
+	}
+
+	return append(lines, "", "Arrows/WASD: move, rotate", "Space: drop  P: pause  Q: quit")
+}
+
+// runTUI plays a game of the mode with the ID in the terminal until the
+// player quits. It uses the board, controller and tick rate of the window,
+// with its own drawing and keyboard input.
+func runTUI(modeID string) error {
+	mode, ok := modeByID(modeID)
+	if !ok {
+		return fmt.Errorf("unknown mode %q", modeID)
+	}
+
+	path, err := configFilePath("settings.json")
+	if err != nil {
+		log.Printf("settings will not be loaded: %v", err)
+	}
+	settings, err := LoadSettings(path)
+	if err != nil {
+		log.Printf("could not load settings: %v", err)
+	}
+
+	path, err = configFilePath("themes")
+	if err != nil {
+		log.Printf("only the built-in themes are available: %v", err)
+	}
+	themes, err := NewThemes(path)
+	if err != nil {
+		log.Printf("could not load themes: %v", err)
+	}
+
+	historyDir, err := configFilePath("history")
+	if err != nil {
+		log.Printf("sessions will not be logged: %v", err)
+	}
+
+	style := &tuiStyle{
+		theme:     themes.Named(settings.Theme),
+		palette:   paletteNamed(settings.Palette),
+		trueColor: slices.Contains([]string{"truecolor", "24bit"}, os.Getenv("COLORTERM")),
+	}
+	game := newTUIGame(mode, settings, historyDir)
+
+	err = playTUI(game, style)
+	if game.err != nil {
+		log.Printf("could not log the session: %v", game.err)
+	}
+
+	return err
+}
+
+// playTUI runs the game loop in the terminal, restoring the terminal when
+// the player quits or the process is told to stop.
+func playTUI(game *tuiGame, style *tuiStyle) error {
+	term, err := openTerminal(os.Stdin)
+	if err != nil {
+		return err
+	}
+	defer term.Restore()
+
+	out := bufio.NewWriter(os.Stdout)
+	out.WriteString(ansiEnter)
+	defer func() {
+		out.WriteString(ansiLeave)
+		out.Flush()
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+
+	keys := make(chan []byte)
+	go readTerminal(os.Stdin, keys)
+
+	width, height, err := term.Size()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Second / ticksPerSecond)
+	defer ticker.Stop()
+
+	var reader termKeyReader
+	var last string
+	redraw := true
+	for tick := 0; !game.quit; {
+		select {
+		case <-stop:
+			return nil
+
+		case <-resized:
+			if width, height, err = term.Size(); err != nil {
+				return err
+			}
+			redraw = true
+
+		case data, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range reader.read(data) {
+				game.handleKey(k)
+			}
+
+		case <-ticker.C:
+			if k := reader.tick(); k != termKeyNone {
+				game.handleKey(k)
+			}
+			game.tick()
+			tick++
+			if tick%tuiFrameTicks != 0 {
+				continue
+			}
+
+			frame := game.frame(width, height, style)
+			if frame == last && !redraw {
+				continue
+			}
+			if redraw {
+				out.WriteString(ansiClear)
+				redraw = false
+			}
+			out.WriteString(ansiHome + frame)
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			last = frame
+		}
+	}
+
+	return nil
+}
+
+// readTerminal sends what is read from r to keys, closing keys when reading
+// fails.
+func readTerminal(r io.Reader, keys chan<- []byte) {
+	defer close(keys)
+
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			keys <- slices.Clone(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
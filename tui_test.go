@@ -0,0 +1,162 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTermKeys(t *testing.T) {
+	keys, rest := parseTermKeys([]byte("a\x1b[D\x1bOAx \x03\x1bp"))
+	want := []termKey{termKeyLeft, termKeyLeft, termKeyUp, termKeySpace, termKeyQuit, termKeyEscape, termKeyPause}
+	if !slices.Equal(keys, want) || len(rest) != 0 {
+		t.Errorf("Expected keys %v, got %v with %q left", want, keys, rest)
+	}
+
+	// A sequence cut off between two reads is parsed with the next bytes
+	keys, rest = parseTermKeys([]byte("d\x1b["))
+	if !slices.Equal(keys, []termKey{termKeyRight}) || string(rest) != "\x1b[" {
+		t.Fatalf("Expected the cut off sequence to be kept, got %v and %q", keys, rest)
+	}
+	keys, _ = parseTermKeys(append(rest, 'B'))
+	if !slices.Equal(keys, []termKey{termKeyDown}) {
+		t.Errorf("Expected the sequence to be completed, got %v", keys)
+	}
+}
+
+func TestTermKeyReader(t *testing.T) {
+	var r termKeyReader
+
+	// A sequence split right after its Esc is still an arrow key
+	if keys := r.read([]byte("\x1b")); len(keys) != 0 {
+		t.Fatalf("Expected a lone Esc to wait for more bytes, got %v", keys)
+	}
+	r.tick()
+	if keys := r.read([]byte("[A")); !slices.Equal(keys, []termKey{termKeyUp}) {
+		t.Errorf("Expected the split sequence to be up, got %v", keys)
+	}
+
+	// A lone Esc is the Esc key once nothing follows it
+	r.read([]byte("\x1b"))
+	for tick := 1; tick < termEscapeTicks; tick++ {
+		if k := r.tick(); k != termKeyNone {
+			t.Fatalf("Expected Esc to wait at tick %d, got %v", tick, k)
+		}
+	}
+	if k := r.tick(); k != termKeyEscape {
+		t.Errorf("Expected Esc after %d ticks, got %v", termEscapeTicks, k)
+	}
+	if k := r.tick(); k != termKeyNone {
+		t.Errorf("Expected Esc only once, got %v", k)
+	}
+}
+
+func TestTermInput(t *testing.T) {
+	in := newTermInput()
+
+	// A move key is held until the terminal stops repeating it
+	in.press(ButtonLeft)
+	for tick := range termHoldTicks {
+		if in.Buttons() != ButtonLeft {
+			t.Fatalf("Expected left to be held at tick %d", tick)
+		}
+		if tick == 1 {
+			in.press(ButtonLeft) // repeated by the terminal
+		}
+	}
+	for range 2 {
+		in.Buttons()
+	}
+	if in.Buttons() != 0 {
+		t.Errorf("Expected left to be released once the repeats stop")
+	}
+
+	// Two rotates in one tick are two presses, with a release in between
+	in.press(ButtonRotate)
+	in.press(ButtonRotate)
+	var got []Buttons
+	for range 4 {
+		got = append(got, in.Buttons())
+	}
+	if want := []Buttons{ButtonRotate, 0, ButtonRotate, 0}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestTUIGame_Keys(t *testing.T) {
+	g := newTUIGame(MarathonMode{}, DefaultSettings(), "")
+	g.tick()
+	x := g.board.currentPiece.x
+
+	g.handleKey(termKeyLeft)
+	g.tick()
+	if g.board.currentPiece.x != x-1 {
+		t.Errorf("Expected the piece to move left")
+	}
+
+	g.handleKey(termKeyPause)
+	if !g.board.paused {
+		t.Fatalf("Expected the game to be paused")
+	}
+	g.handleKey(termKeySpace)
+	g.tick()
+	if g.board.piecesPlaced != 0 {
+		t.Errorf("Expected no hard drop while paused")
+	}
+
+	g.board.gameOver = true
+	g.handleKey(termKeyEnter)
+	if g.board.gameOver || g.quit {
+		t.Errorf("Expected Enter to start a new game after a game over")
+	}
+
+	g.handleKey(termKeyQuit)
+	if !g.quit {
+		t.Errorf("Expected Q to quit")
+	}
+}
+
+func TestTUIGame_Frame(t *testing.T) {
+	themes, err := NewThemes("")
+	if err != nil {
+		t.Fatalf("Could not load the built-in themes: %v", err)
+	}
+	style := &tuiStyle{theme: themes.Named(defaultTheme)}
+	g := newTUIGame(SprintMode{Lines: 40}, DefaultSettings(), "")
+	ansi := regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+	tests := []struct {
+		name          string
+		width, height int
+		lines, width0 int // lines of the frame, characters of the board line
+	}{
+		{name: "full tiles", width: 80, height: 30, lines: rows + 2, width0: 2*cols + 2},
+		{name: "half blocks", width: 80, height: 20, lines: rows/2 + 2, width0: cols + 2},
+		{name: "too small", width: 30, height: 10, lines: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := ansi.ReplaceAllString(g.frame(tt.width, tt.height, style), "")
+			lines := strings.Split(frame, "\r\n")
+			if len(lines) != tt.lines {
+				t.Fatalf("Expected %d lines, got %d:\n%s", tt.lines, len(lines), frame)
+			}
+			for _, line := range lines {
+				if n := len([]rune(line)); n > tt.width {
+					t.Errorf("Expected lines to fit %d columns, got %d", tt.width, n)
+				}
+			}
+			if tt.width0 > 0 {
+				if board, _, _ := strings.Cut(lines[0], " "); len([]rune(board)) != tt.width0 {
+					t.Errorf("Expected the board to be %d wide, got %q", tt.width0, board)
+				}
+			}
+		})
+	}
+
+	if frame := g.frame(80, 30, style); !strings.Contains(frame, "SPRINT 40L") || !strings.Contains(frame, "3") {
+		t.Errorf("Expected the title and countdown next to the board")
+	}
+}
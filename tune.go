@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// botWeightCount is the number of features in BotWeights.
+const botWeightCount = 6
+
+func (w BotWeights) vector() []float64 {
+	return []float64{w.LandingHeight, w.ErodedCells, w.RowTransitions, w.ColumnTransitions, w.Holes, w.Wells}
+}
+
+func botWeightsFromVector(v []float64) BotWeights {
+	return BotWeights{
+		LandingHeight:     v[0],
+		ErodedCells:       v[1],
+		RowTransitions:    v[2],
+		ColumnTransitions: v[3],
+		Holes:             v[4],
+		Wells:             v[5],
+	}
+}
+
+// LoadBotWeights reads tuned weights from path, falling back to the default
+// weights for a missing file.
+func LoadBotWeights(path string) (BotWeights, error) {
+	if path == "" {
+		return defaultBotWeights, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultBotWeights, nil
+	}
+	if err != nil {
+		return defaultBotWeights, err
+	}
+
+	w := defaultBotWeights
+	if err := json.Unmarshal(data, &w); err != nil {
+		return defaultBotWeights, err
+	}
+
+	return w, nil
+}
+
+func saveBotWeights(path string, w BotWeights) error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// simulateGame lets the bot place up to maxPieces pieces on a seeded board
+// without gravity or input, and returns the lines it cleared.
+func simulateGame(w BotWeights, seed int64, maxPieces int) int {
+	b := NewBoardWithSeed(rows, cols, seed)
+
+	for b.piecesPlaced < maxPieces && !b.gameOver {
+		p, ok := b.BestPlacement(w)
+		if !ok {
+			break
+		}
+
+		b.currentPiece = &p.piece
+		b.Fall()
+	}
+
+	return b.totalNumberOfLinesCleared
+}
+
+// TuneOptions configure a run of the genetic algorithm.
+type TuneOptions struct {
+	Generations int
+	Population  int
+	Games       int // games played by each candidate per generation
+	Pieces      int // pieces per game
+	Workers     int
+	Seed        int64
+}
+
+type tuneCandidate struct {
+	weights []float64 // normalised to length 1
+	fitness float64   // average lines cleared
+}
+
+// Tune evolves bot weights with a genetic algorithm: every generation all
+// candidates play the same seeded games, and the worst are replaced by
+// children of tournament winners. It calls progress after every generation
+// and returns the best weights found, or the default weights when no
+// generation ran.
+func Tune(opts TuneOptions, progress func(generation int, best, mean float64, w BotWeights)) BotWeights {
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	population := make([]tuneCandidate, opts.Population)
+	population[0].weights = normalise(defaultBotWeights.vector())
+	for i := 1; i < len(population); i++ {
+		v := make([]float64, botWeightCount)
+		for j := range v {
+			v[j] = rng.Float64()*2 - 1
+		}
+		population[i].weights = normalise(v)
+	}
+
+	best := tuneCandidate{fitness: -1}
+
+	for generation := 1; generation <= opts.Generations; generation++ {
+		seeds := make([]int64, opts.Games)
+		for i := range seeds {
+			seeds[i] = rng.Int63()
+		}
+
+		evaluate(population, seeds, opts)
+		slices.SortFunc(population, func(a, b tuneCandidate) int {
+			return compareFloats(b.fitness, a.fitness)
+		})
+
+		if population[0].fitness > best.fitness {
+			best = population[0]
+		}
+
+		if progress != nil {
+			mean := 0.0
+			for _, c := range population {
+				mean += c.fitness
+			}
+			progress(generation, population[0].fitness, mean/float64(len(population)), botWeightsFromVector(population[0].weights))
+		}
+
+		// Replace the worst 30% with children of the best
+		children := len(population) * 3 / 10
+		for i := len(population) - children; i < len(population); i++ {
+			a := tournament(rng, population)
+			b := tournament(rng, population)
+			population[i] = tuneCandidate{weights: crossover(rng, a, b)}
+		}
+	}
+
+	if best.weights == nil {
+		return defaultBotWeights
+	}
+
+	return botWeightsFromVector(best.weights)
+}
+
+// evaluate plays every game of every candidate on a pool of workers.
+func evaluate(population []tuneCandidate, seeds []int64, opts TuneOptions) {
+	type job struct{ candidate, game int }
+	jobs := make(chan job)
+	lines := make([][]int, len(population))
+	for i := range lines {
+		lines[i] = make([]int, len(seeds))
+	}
+
+	var wg sync.WaitGroup
+	for range max(opts.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				w := botWeightsFromVector(population[j.candidate].weights)
+				lines[j.candidate][j.game] = simulateGame(w, seeds[j.game], opts.Pieces)
+			}
+		}()
+	}
+
+	for candidate := range population {
+		for game := range seeds {
+			jobs <- job{candidate, game}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, games := range lines {
+		total := 0
+		for _, n := range games {
+			total += n
+		}
+		population[i].fitness = float64(total) / float64(len(games))
+	}
+}
+
+// tournament picks the fittest of a random 10% of the population.
+func tournament(rng *rand.Rand, population []tuneCandidate) tuneCandidate {
+	best := population[rng.Intn(len(population))]
+	for range max(len(population)/10, 1) - 1 {
+		c := population[rng.Intn(len(population))]
+		if c.fitness > best.fitness {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// crossover mixes two parents weighted by their fitness, then sometimes
+// mutates one of the weights.
+func crossover(rng *rand.Rand, a, b tuneCandidate) []float64 {
+	fa, fb := a.fitness, b.fitness
+	if fa+fb == 0 {
+		fa, fb = 1, 1
+	}
+
+	child := make([]float64, botWeightCount)
+	for i := range child {
+		child[i] = a.weights[i]*fa + b.weights[i]*fb
+	}
+
+	if rng.Float64() < 0.05 {
+		child[rng.Intn(len(child))] += (rng.Float64()*2 - 1) * 0.2
+	}
+
+	return normalise(child)
+}
+
+func normalise(v []float64) []float64 {
+	length := 0.0
+	for _, x := range v {
+		length += x * x
+	}
+	length = math.Sqrt(length)
+	if length == 0 {
+		return v
+	}
+
+	for i := range v {
+		v[i] /= length
+	}
+
+	return v
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// runTuneCommand is the "tune" subcommand. It runs headless and writes the
+// best weights where the game picks them up for its bot.
+func runTuneCommand(args []string) error {
+	out, err := configFilePath("bot_weights.json")
+	if err != nil {
+		out = "bot_weights.json"
+	}
+
+	flags := flag.NewFlagSet("tune", flag.ExitOnError)
+	opts := TuneOptions{}
+	flags.IntVar(&opts.Generations, "generations", 20, "number of generations")
+	flags.IntVar(&opts.Population, "population", 50, "candidates per generation")
+	flags.IntVar(&opts.Games, "games", 10, "games each candidate plays per generation")
+	flags.IntVar(&opts.Pieces, "pieces", 500, "pieces per game")
+	flags.IntVar(&opts.Workers, "workers", runtime.NumCPU(), "games played in parallel")
+	flags.Int64Var(&opts.Seed, "seed", time.Now().UnixNano(), "random seed")
+	flags.StringVar(&out, "out", out, "file the best weights are written to")
+	flags.Parse(args)
+
+	if opts.Generations < 1 || opts.Population < 2 || opts.Games < 1 || opts.Pieces < 1 {
+		return errors.New("tune needs a population of at least 2 and at least one generation, game and piece")
+	}
+
+	start := time.Now()
+	best := Tune(opts, func(generation int, best, mean float64, w BotWeights) {
+		fmt.Printf("generation %d/%d: best %.1f lines, mean %.1f, %s\n", generation, opts.Generations, best, mean, time.Since(start).Round(time.Second))
+		fmt.Printf("  %+v\n", w)
+	})
+
+	if err := saveBotWeights(out, best); err != nil {
+		return err
+	}
+	fmt.Printf("best weights written to %s\n", out)
+
+	return nil
+}
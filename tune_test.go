@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestTune_ImprovesOrKeepsTheBest(t *testing.T) {
+	generations := 0
+	best := Tune(TuneOptions{Generations: 3, Population: 6, Games: 2, Pieces: 60, Workers: 4, Seed: 1},
+		func(generation int, best, mean float64, w BotWeights) {
+			generations++
+			if best < mean {
+				t.Errorf("generation %d: best %.1f is below the mean %.1f", generation, best, mean)
+			}
+		})
+
+	if generations != 3 {
+		t.Errorf("progress called %d times, want 3", generations)
+	}
+
+	length := 0.0
+	for _, x := range best.vector() {
+		length += x * x
+	}
+	if math.Abs(length-1) > 1e-9 {
+		t.Errorf("best weights have length %f, want 1", math.Sqrt(length))
+	}
+}
+
+func TestTune_NoGenerations(t *testing.T) {
+	for _, generations := range []int{0, -1} {
+		best := Tune(TuneOptions{Generations: generations, Population: 6, Games: 1, Pieces: 10, Seed: 1}, nil)
+		if best != defaultBotWeights {
+			t.Errorf("%d generations: got %+v, want the default weights", generations, best)
+		}
+	}
+}
+
+func TestBotWeights_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weights.json")
+
+	w, err := LoadBotWeights(path)
+	if err != nil || w != defaultBotWeights {
+		t.Fatalf("missing file: got %+v, %v; want the defaults", w, err)
+	}
+
+	tuned := botWeightsFromVector([]float64{-1, 2, -3, -4, -5, -6})
+	if err := saveBotWeights(path, tuned); err != nil {
+		t.Fatal(err)
+	}
+
+	if w, err := LoadBotWeights(path); err != nil || w != tuned {
+		t.Errorf("got %+v, %v; want %+v", w, err, tuned)
+	}
+}
+
+func BenchmarkCheckCollision(b *testing.B) {
+	board := NewBoardWithSeed(rows, cols, 1)
+	p := &FallingPiece{piece: board.tiles[PieceT], x: spawnX, y: rows / 2}
+
+	for b.Loop() {
+		board.checkCollision(p, 1, 0)
+	}
+}
+
+// BenchmarkAddCurrentPieceToTheBoard locks O pieces side by side, so every
+// fifth lock clears two lines.
+func BenchmarkAddCurrentPieceToTheBoard(b *testing.B) {
+	board := NewBoardWithSeed(rows, cols, 1)
+	b.ReportAllocs()
+
+	p := &FallingPiece{}
+	i := 0
+	for b.Loop() {
+		*p = board.dropPosition(FallingPiece{piece: board.tiles[PieceO], x: float64(i % 5 * 2), y: 1})
+		board.currentPiece = p
+		board.addCurrentPieceToTheBoard()
+		i++
+	}
+}
+
+func BenchmarkBestPlacement(b *testing.B) {
+	board := NewBoardWithSeed(rows, cols, 1)
+	b.ReportAllocs()
+
+	for b.Loop() {
+		board.BestPlacement(defaultBotWeights)
+	}
+}
+
+func BenchmarkSimulateGame(b *testing.B) {
+	b.ReportAllocs()
+
+	for b.Loop() {
+		simulateGame(defaultBotWeights, 1, 100)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const versusPlayers = 2
+
+// versusBestOf are the match lengths of a local versus game.
+var versusBestOf = []int{1, 3, 5, 7}
+
+// versusScene is a local split-screen game of two players. Both boards get
+// the same pieces and send each other garbage for their clears.
+type versusScene struct {
+	bestOf    int
+	boards    [versusPlayers]*Board
+	inputs    [versusPlayers]*InputHandler
+	renderers [versusPlayers]*Renderer
+	wins      [versusPlayers]int
+	sent      [versusPlayers]int
+	roundOver bool
+	winner    int // -1 for a draw
+	pauseMenu *Menu
+}
+
+func newVersusScene(g *Game, bestOf int) *versusScene {
+	s := &versusScene{bestOf: bestOf}
+
+	for i := range versusPlayers {
+		s.inputs[i] = NewPlayerInputHandler(g.settings, versusControls[i])
+		s.renderers[i] = NewCompactRenderer(rows, cols)
+		s.renderers[i].settings = g.settings
+	}
+
+	s.pauseMenu = NewMenu("PAUSED",
+		MenuItem{Label: "Resume", Select: s.togglePause},
+		MenuItem{Label: "Restart", Select: func() { g.startVersus(s.bestOf) }},
+		MenuItem{Label: "Quit", Select: g.quitToTitle},
+	)
+
+	s.newRound()
+
+	return s
+}
+
+func (s *versusScene) newRound() {
+	seed := rand.Int63()
+
+	for i := range versusPlayers {
+		s.boards[i] = NewBoardWithSeed(rows, cols, seed)
+		s.boards[i].countdown = countdownTicks
+		s.renderers[i].Watch(s.boards[i])
+		s.inputs[i].Reset()
+		s.sent[i] = 0
+	}
+
+	s.roundOver = false
+}
+
+// winsNeeded returns the rounds a player has to win to take the match.
+func (s *versusScene) winsNeeded() int {
+	return s.bestOf/2 + 1
+}
+
+func (s *versusScene) matchOver() bool {
+	return s.wins[0] >= s.winsNeeded() || s.wins[1] >= s.winsNeeded()
+}
+
+func (s *versusScene) togglePause() {
+	for _, b := range s.boards {
+		b.TogglePause()
+	}
+}
+
+func (s *versusScene) Update(g *Game) error {
+	if s.roundOver {
+		switch g.inputHandler.MenuAction() {
+		case menuConfirm:
+			if s.matchOver() {
+				g.startVersus(s.bestOf)
+			} else {
+				s.newRound()
+			}
+		case menuBack:
+			g.quitToTitle()
+		}
+		return nil
+	}
+
+	for i, b := range s.boards {
+		s.inputs[i].Update(b)
+	}
+
+	if s.boards[0].paused {
+		action := g.inputHandler.MenuAction()
+		if s.pauseMenu.Handle(action) || (action == menuNone && g.inputHandler.PausePressed()) {
+			s.togglePause()
+		}
+		return nil
+	}
+
+	if g.inputHandler.PausePressed() {
+		s.togglePause()
+		s.pauseMenu.Reset()
+		return nil
+	}
+
+	for i, b := range s.boards {
+		b.Tick()
+		s.renderers[i].UpdateAnimations()
+	}
+
+	// Attack the other player
+	for i, b := range s.boards {
+		lines := b.TakeOutgoingGarbage()
+		s.sent[i] += lines
+		s.boards[(i+1)%versusPlayers].QueueGarbage(lines)
+	}
+
+	s.checkRoundOver()
+
+	return nil
+}
+
+func (s *versusScene) checkRoundOver() {
+	lost := [versusPlayers]bool{}
+	for i, b := range s.boards {
+		lost[i] = b.gameOver
+	}
+
+	switch {
+	case lost[0] && lost[1]:
+		s.winner = -1
+	case lost[0]:
+		s.winner = 1
+	case lost[1]:
+		s.winner = 0
+	default:
+		return
+	}
+
+	s.roundOver = true
+	if s.winner >= 0 {
+		s.wins[s.winner]++
+	}
+}
+
+func (s *versusScene) Draw(g *Game, screen *ebiten.Image) {
+	screen.Fill(bgColor)
+
+	for i, b := range s.boards {
+		hud := []HUDItem{
+			{Label: "WINS", Value: fmt.Sprintf("%d/%d", s.wins[i], s.winsNeeded())},
+			{Label: "SENT", Value: fmt.Sprintf("%d", s.sent[i])},
+		}
+		s.renderers[i].Place(splitArea(screen.Bounds(), versusPlayers, i))
+		s.renderers[i].DrawBoard(screen, b, hud)
+	}
+
+	if s.roundOver {
+		g.renderer.dimScreen(screen)
+		g.renderer.DrawVersusResult(screen, s.winner, s.matchOver())
+	}
+
+	if s.boards[0].paused {
+		g.renderer.dimScreen(screen)
+		g.renderer.DrawMenu(screen, s.pauseMenu)
+	}
+}